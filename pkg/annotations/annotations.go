@@ -0,0 +1,130 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package annotations defines the Ingress annotations the GCE Ingress
+// controller understands and the accessors used to read them.
+package annotations
+
+import "strconv"
+
+const (
+	// StaticIPNameKey tells the Ingress controller to use an existing
+	// static global address instead of creating a new one.
+	StaticIPNameKey = "kubernetes.io/ingress.global-static-ip-name"
+
+	// SSLRedirectKey, when set to "true", tells the Ingress controller to
+	// redirect all HTTP traffic for this Ingress to HTTPS on the same
+	// host and path rather than forwarding it to a backend. It only takes
+	// effect when the Ingress also has a usable HTTPS frontend (at least
+	// one TLS entry or a configured static IP).
+	SSLRedirectKey = "ingress.kubernetes.io/force-ssl-redirect"
+
+	// SSLRedirectResponseCodeKey overrides the HTTP status code used for
+	// the force-ssl-redirect. One of "301", "302", "307", "308". Defaults
+	// to DefaultSSLRedirectResponseCode when unset or invalid.
+	SSLRedirectResponseCodeKey = "ingress.kubernetes.io/ssl-redirect-code"
+
+	// DefaultSSLRedirectResponseCode is used when SSLRedirectResponseCodeKey
+	// is absent or carries an unrecognized value.
+	DefaultSSLRedirectResponseCode = "301"
+
+	// PassTLSCertKey, when set to "true", tells the Ingress controller to
+	// forward the client's TLS certificate to the backend in a header, so
+	// that applications behind the GCE L7 can implement their own
+	// app-level client-cert (mTLS) checks.
+	PassTLSCertKey = "ingress.kubernetes.io/pass-tls-cert"
+
+	// PassTLSHeaderNameKey overrides the header name the client
+	// certificate is forwarded in. Defaults to DefaultPassTLSHeaderName.
+	PassTLSHeaderNameKey = "ingress.kubernetes.io/pass-tls-header-name"
+
+	// DefaultPassTLSHeaderName is used when PassTLSHeaderNameKey is unset.
+	DefaultPassTLSHeaderName = "X-Forwarded-Client-Cert"
+
+	// ClientCertTrustSecretNameKey names the Secret (in the Ingress's own
+	// namespace) whose "ca.crt" entry is the CA bundle client certificates
+	// are validated against when PassTLSCertKey is enabled. Without it,
+	// PassTLSCertKey has no CA to validate against and is ignored.
+	ClientCertTrustSecretNameKey = "ingress.kubernetes.io/pass-tls-trust-secret"
+)
+
+// validRedirectResponseCodes enumerates the values SSLRedirectResponseCodeKey
+// accepts.
+var validRedirectResponseCodes = map[string]bool{
+	"301": true,
+	"302": true,
+	"307": true,
+	"308": true,
+}
+
+// IngressAnnotations wraps the map of annotations on an Ingress object and
+// provides typed accessors for the keys this controller understands.
+type IngressAnnotations map[string]string
+
+// StaticIPName returns the value of StaticIPNameKey, or "" if it is unset.
+func (ing IngressAnnotations) StaticIPName() string {
+	return ing[StaticIPNameKey]
+}
+
+// ForceSSLRedirect returns true if the Ingress requests that all HTTP
+// traffic be redirected to HTTPS.
+func (ing IngressAnnotations) ForceSSLRedirect() bool {
+	v, ok := ing[SSLRedirectKey]
+	if !ok {
+		return false
+	}
+	redirect, err := strconv.ParseBool(v)
+	return err == nil && redirect
+}
+
+// SSLRedirectResponseCode returns the HTTP status code the redirect should
+// use, falling back to DefaultSSLRedirectResponseCode if the annotation is
+// absent or not one of the values GCE's HttpRedirectAction accepts.
+func (ing IngressAnnotations) SSLRedirectResponseCode() string {
+	v, ok := ing[SSLRedirectResponseCodeKey]
+	if !ok || !validRedirectResponseCodes[v] {
+		return DefaultSSLRedirectResponseCode
+	}
+	return v
+}
+
+// PassTLSCert returns true if the Ingress requests that the client's TLS
+// certificate be forwarded to its backends in a header.
+func (ing IngressAnnotations) PassTLSCert() bool {
+	v, ok := ing[PassTLSCertKey]
+	if !ok {
+		return false
+	}
+	pass, err := strconv.ParseBool(v)
+	return err == nil && pass
+}
+
+// PassTLSHeaderName returns the header name the client certificate should
+// be forwarded in, falling back to DefaultPassTLSHeaderName if
+// PassTLSHeaderNameKey is unset.
+func (ing IngressAnnotations) PassTLSHeaderName() string {
+	if v, ok := ing[PassTLSHeaderNameKey]; ok && v != "" {
+		return v
+	}
+	return DefaultPassTLSHeaderName
+}
+
+// ClientCertTrustSecretName returns the name of the Secret whose CA bundle
+// client certificates should be validated against, or "" if the Ingress
+// didn't reference one.
+func (ing IngressAnnotations) ClientCertTrustSecretName() string {
+	return ing[ClientCertTrustSecretNameKey]
+}
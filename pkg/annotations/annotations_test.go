@@ -0,0 +1,83 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package annotations
+
+import "testing"
+
+func TestForceSSLRedirect(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ing  IngressAnnotations
+		want bool
+	}{
+		{"unset", IngressAnnotations{}, false},
+		{"true", IngressAnnotations{SSLRedirectKey: "true"}, true},
+		{"false", IngressAnnotations{SSLRedirectKey: "false"}, false},
+		{"garbage", IngressAnnotations{SSLRedirectKey: "yes please"}, false},
+	} {
+		if got := tc.ing.ForceSSLRedirect(); got != tc.want {
+			t.Errorf("%s: ForceSSLRedirect() = %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestSSLRedirectResponseCode(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ing  IngressAnnotations
+		want string
+	}{
+		{"unset", IngressAnnotations{}, DefaultSSLRedirectResponseCode},
+		{"valid", IngressAnnotations{SSLRedirectResponseCodeKey: "307"}, "307"},
+		{"invalid falls back to default", IngressAnnotations{SSLRedirectResponseCodeKey: "418"}, DefaultSSLRedirectResponseCode},
+	} {
+		if got := tc.ing.SSLRedirectResponseCode(); got != tc.want {
+			t.Errorf("%s: SSLRedirectResponseCode() = %q, want %q", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestPassTLSCert(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ing  IngressAnnotations
+		want bool
+	}{
+		{"unset", IngressAnnotations{}, false},
+		{"true", IngressAnnotations{PassTLSCertKey: "true"}, true},
+		{"false", IngressAnnotations{PassTLSCertKey: "false"}, false},
+	} {
+		if got := tc.ing.PassTLSCert(); got != tc.want {
+			t.Errorf("%s: PassTLSCert() = %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestPassTLSHeaderName(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ing  IngressAnnotations
+		want string
+	}{
+		{"unset", IngressAnnotations{}, DefaultPassTLSHeaderName},
+		{"custom", IngressAnnotations{PassTLSHeaderNameKey: "X-Client-Cert"}, "X-Client-Cert"},
+	} {
+		if got := tc.ing.PassTLSHeaderName(); got != tc.want {
+			t.Errorf("%s: PassTLSHeaderName() = %q, want %q", tc.desc, got, tc.want)
+		}
+	}
+}
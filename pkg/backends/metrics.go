@@ -0,0 +1,32 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// backendRefCount exposes, per Service name, the number of Ingresses
+// currently sharing that Service's backend. It lets operators see which
+// backends are the most widely shared, and notice one whose refcount never
+// reaches zero even after its Ingresses are gone.
+var backendRefCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "ingress_shared_backend_refs",
+	Help: "Number of Ingresses currently referencing a shared backend, labeled by Service name.",
+}, []string{"service_name"})
+
+func init() {
+	prometheus.MustRegister(backendRefCount)
+}
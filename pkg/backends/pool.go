@@ -0,0 +1,113 @@
+/*
+Copyright 2015 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backends manages the GCE BackendServices fronting Ingress-
+// referenced node ports.
+package backends
+
+import (
+	"fmt"
+	"sync"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+// Namer names the BackendService a Pool creates for a node port.
+type Namer interface {
+	IGBackend(nodePort int64) string
+}
+
+// Pool manages the GCE BackendServices backing Ingress rules' node ports.
+// Backends are shared across Ingresses: two Ingresses that both have a rule
+// pointing at the same node port share the same BackendService, and it's
+// only garbage collected once refs says no Ingress depends on it any more.
+type Pool struct {
+	mu    sync.Mutex
+	namer Namer
+	refs  *RefCountIndex
+
+	backends map[string]*compute.BackendService
+}
+
+// NewPool returns an empty Pool, gating GC on refs.
+func NewPool(namer Namer, refs *RefCountIndex) *Pool {
+	return &Pool{namer: namer, refs: refs, backends: map[string]*compute.BackendService{}}
+}
+
+// Ensure creates the BackendService for nodePort if it doesn't already
+// exist, and returns it either way.
+func (p *Pool) Ensure(nodePort int64) (*compute.BackendService, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := p.namer.IGBackend(nodePort)
+	if be, ok := p.backends[name]; ok {
+		return be, nil
+	}
+	be := &compute.BackendService{Name: name, Port: nodePort}
+	p.backends[name] = be
+	return be, nil
+}
+
+// Get returns the BackendService named name. forceUpdate is accepted for
+// parity with the production pool's cache-invalidation parameter; this
+// in-memory pool has no cache to invalidate.
+func (p *Pool) Get(name string, forceUpdate bool) (*compute.BackendService, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	be, ok := p.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("no BackendService named %q", name)
+	}
+	return be, nil
+}
+
+// GC deletes every BackendService whose refcount (summed across every
+// Service name sharing its node port, via refs.CountByPort) has reached
+// zero.
+func (p *Pool) GC() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, be := range p.backends {
+		if p.refs.CountByPort(be.Port) > 0 {
+			continue
+		}
+		delete(p.backends, name)
+	}
+	return nil
+}
+
+// Refs returns the Ingress keys currently depending on the backend for
+// nodePort, for debugging a backend that won't garbage collect.
+func (p *Pool) Refs(nodePort int64) []string {
+	return p.refs.Refs(nodePort)
+}
+
+// NodePorts returns the node port of every BackendService currently
+// tracked. The firewall pool opens exactly this set, so node ports GC
+// already dropped don't linger open.
+func (p *Pool) NodePorts() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ports := make([]int64, 0, len(p.backends))
+	for _, be := range p.backends {
+		ports = append(ports, be.Port)
+	}
+	return ports
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import "sync"
+
+// refKey identifies a shared backend by the Service it fronts, rather than
+// by node port alone, so that two different Services that happen to have
+// landed on the same node port (e.g. across two short-lived resyncs)
+// don't get attributed to each other's refcount.
+type refKey struct {
+	NodePort int64
+	SvcName  string
+}
+
+// RefCountIndex tracks, for each backend (nodePort, svcName) pair, the set
+// of Ingress keys currently depending on it. LoadBalancerController
+// maintains it on every Ingress add/update/delete so that backendPool can
+// gate a backend's deletion on its refcount reaching zero, instead of
+// re-scanning every Ingress in the lister on every sync -- an O(N·M) sweep
+// that races with an in-flight sync on a different Ingress sharing the
+// same backend.
+type RefCountIndex struct {
+	mu   sync.Mutex
+	refs map[refKey]map[string]bool
+}
+
+// NewRefCountIndex returns an empty RefCountIndex.
+func NewRefCountIndex() *RefCountIndex {
+	return &RefCountIndex{refs: map[refKey]map[string]bool{}}
+}
+
+// Add records that ingKey depends on the backend serving svcName on
+// nodePort.
+func (r *RefCountIndex) Add(nodePort int64, svcName, ingKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := refKey{NodePort: nodePort, SvcName: svcName}
+	if r.refs[k] == nil {
+		r.refs[k] = map[string]bool{}
+	}
+	r.refs[k][ingKey] = true
+	backendRefCount.WithLabelValues(svcName).Set(float64(len(r.refs[k])))
+}
+
+// Remove drops ingKey's dependency on the backend serving svcName on
+// nodePort. It's a no-op if ingKey was never recorded.
+func (r *RefCountIndex) Remove(nodePort int64, svcName, ingKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := refKey{NodePort: nodePort, SvcName: svcName}
+	delete(r.refs[k], ingKey)
+	if len(r.refs[k]) == 0 {
+		delete(r.refs, k)
+		backendRefCount.DeleteLabelValues(svcName)
+		return
+	}
+	backendRefCount.WithLabelValues(svcName).Set(float64(len(r.refs[k])))
+}
+
+// Count returns the number of Ingresses currently depending on the backend
+// serving svcName on nodePort. backendPool should only delete a backend
+// once this is zero.
+func (r *RefCountIndex) Count(nodePort int64, svcName string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.refs[refKey{NodePort: nodePort, SvcName: svcName}])
+}
+
+// CountByPort returns the total number of Ingresses depending on the
+// backend for nodePort, summed across every Service name that has
+// registered a dependency on it. backends.Pool.GC gates a BackendService's
+// deletion on this reaching zero rather than on Count(nodePort, svcName),
+// since the same node port can end up registered under more than one
+// Service name as Services and Ingresses churn.
+func (r *RefCountIndex) CountByPort(nodePort int64) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := 0
+	for k, ings := range r.refs {
+		if k.NodePort == nodePort {
+			total += len(ings)
+		}
+	}
+	return total
+}
+
+// RemoveIngress drops every dependency ingKey has registered, across every
+// (nodePort, svcName) pair. LoadBalancerController calls this when an
+// Ingress is deleted, since by then the Ingress object -- and the rules
+// that would otherwise say which backends to release -- is already gone
+// from the lister.
+func (r *RefCountIndex) RemoveIngress(ingKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, ings := range r.refs {
+		if !ings[ingKey] {
+			continue
+		}
+		delete(ings, ingKey)
+		if len(ings) == 0 {
+			delete(r.refs, k)
+			backendRefCount.DeleteLabelValues(k.SvcName)
+		} else {
+			backendRefCount.WithLabelValues(k.SvcName).Set(float64(len(ings)))
+		}
+	}
+}
+
+// Refs returns the Ingress keys currently depending on the backend for
+// nodePort, across every Service name it has been registered under. It
+// exists for debugging a backend that won't garbage collect; production
+// code should prefer Count.
+func (r *RefCountIndex) Refs(nodePort int64) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []string
+	for k, ings := range r.refs {
+		if k.NodePort != nodePort {
+			continue
+		}
+		for ingKey := range ings {
+			out = append(out, ingKey)
+		}
+	}
+	return out
+}
+
+// Rebuild clears the index and repopulates it via scan, which is expected
+// to call the add func it's handed once per (nodePort, svcName, ingKey)
+// triple it finds. LoadBalancerController calls this once against its
+// Ingress lister on start, so refcounts survive a controller restart
+// instead of starting at zero and potentially letting a still-referenced
+// backend get garbage collected before its dependent Ingresses resync.
+func (r *RefCountIndex) Rebuild(scan func(add func(nodePort int64, svcName, ingKey string))) {
+	r.mu.Lock()
+	r.refs = map[refKey]map[string]bool{}
+	r.mu.Unlock()
+
+	scan(r.Add)
+}
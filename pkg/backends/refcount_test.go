@@ -0,0 +1,83 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRefCountIndexAddRemove(t *testing.T) {
+	idx := NewRefCountIndex()
+	idx.Add(30001, "foosvc", "ns/ingA")
+	if got := idx.Count(30001, "foosvc"); got != 1 {
+		t.Fatalf("idx.Count(...) = %d, want 1", got)
+	}
+
+	idx.Remove(30001, "foosvc", "ns/ingA")
+	if got := idx.Count(30001, "foosvc"); got != 0 {
+		t.Fatalf("idx.Count(...) = %d, want 0 after Remove", got)
+	}
+}
+
+// TestRefCountIndexConcurrentChurnNeverDropsLiveRef interleaves repeated
+// add/remove cycles for ingress A with a steady ref held by ingress B on
+// the same (nodePort, svcName) backend, mirroring two Ingresses sharing a
+// Service while one of them is being resynced (e.g. losing a path that
+// used the shared backend). ingress B's ref must never be observed as
+// dropped.
+func TestRefCountIndexConcurrentChurnNeverDropsLiveRef(t *testing.T) {
+	idx := NewRefCountIndex()
+	idx.Add(30001, "foosvc", "ns/ingB")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx.Add(30001, "foosvc", "ns/ingA")
+			idx.Remove(30001, "foosvc", "ns/ingA")
+		}()
+	}
+	wg.Wait()
+
+	if got := idx.Count(30001, "foosvc"); got != 1 {
+		t.Fatalf("idx.Count(...) = %d, want 1; ingress B's ref must survive concurrent churn from ingress A", got)
+	}
+	if refs := idx.Refs(30001); len(refs) != 1 || refs[0] != "ns/ingB" {
+		t.Fatalf("idx.Refs(30001) = %v, want [ns/ingB]", refs)
+	}
+}
+
+func TestRefCountIndexRebuildRecoversFromLister(t *testing.T) {
+	idx := NewRefCountIndex()
+	// Simulates refcount state left over from before a controller
+	// restart; Rebuild should discard it in favor of a fresh scan.
+	idx.Add(30001, "staleSvc", "ns/staleIng")
+
+	idx.Rebuild(func(add func(nodePort int64, svcName, ingKey string)) {
+		add(30002, "foosvc", "ns/ingA")
+		add(30002, "foosvc", "ns/ingB")
+	})
+
+	if got := idx.Count(30001, "staleSvc"); got != 0 {
+		t.Fatalf("idx.Count(staleSvc) = %d, want 0 after Rebuild", got)
+	}
+	if got := idx.Count(30002, "foosvc"); got != 2 {
+		t.Fatalf("idx.Count(foosvc) = %d, want 2 after Rebuild", got)
+	}
+}
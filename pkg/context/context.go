@@ -0,0 +1,71 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package context holds the informers and clients shared across this
+// controller's Ingress, Service, and Secret watchers.
+package context
+
+import (
+	"time"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ControllerContext holds the shared clientset and informers
+// LoadBalancerController (and anything else watching Ingress-related
+// objects) is built on top of.
+type ControllerContext struct {
+	KubeClient kubernetes.Interface
+
+	IngressInformer cache.SharedIndexInformer
+	ServiceInformer cache.SharedIndexInformer
+	SecretInformer  cache.SharedIndexInformer
+
+	informerFactory informers.SharedInformerFactory
+}
+
+// NewControllerContext returns a ControllerContext whose informers are
+// registered, but not yet started -- call Start once every controller
+// consuming it has registered its event handlers. enableEndpointsInformer is
+// accepted for parity with the upstream constructor signature; this tree
+// doesn't yet drive anything off Endpoints.
+func NewControllerContext(kubeClient kubernetes.Interface, namespace string, resyncPeriod time.Duration, enableEndpointsInformer bool) *ControllerContext {
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, resyncPeriod, informers.WithNamespace(namespace))
+
+	return &ControllerContext{
+		KubeClient:      kubeClient,
+		IngressInformer: factory.Extensions().V1beta1().Ingresses().Informer(),
+		ServiceInformer: factory.Core().V1().Services().Informer(),
+		SecretInformer:  factory.Core().V1().Secrets().Informer(),
+		informerFactory: factory,
+	}
+}
+
+// Start begins processing events from every registered informer. It must be
+// called exactly once, after every controller consuming this context has
+// registered its event handlers.
+func (ctx *ControllerContext) Start(stopCh chan struct{}) {
+	ctx.informerFactory.Start(stopCh)
+}
+
+// HasSynced reports whether every informer's initial list has completed.
+func (ctx *ControllerContext) HasSynced() bool {
+	return ctx.IngressInformer.HasSynced() &&
+		ctx.ServiceInformer.HasSynced() &&
+		ctx.SecretInformer.HasSynced()
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-gce/pkg/annotations"
+	"k8s.io/ingress-gce/pkg/flags"
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+	"k8s.io/ingress-gce/pkg/tls"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// TestLbClientCertPassThrough is in the style of TestLbChangeStaticIP: it
+// drives an Ingress carrying the pass-tls-cert annotation and a trusted-CA
+// Secret through a real sync and asserts on the fake LB's resulting
+// ServerTLSPolicy state, rather than calling ensureClientTLSPolicy directly
+// the way pkg/loadbalancers/tls_passthrough_test.go does.
+func TestLbClientCertPassThrough(t *testing.T) {
+	cm := NewFakeClusterManager(flags.DefaultClusterUID, DefaultFirewallName)
+	lbc := newLoadBalancerController(t, cm)
+	pm := newPortManager(1, 65536, cm.Namer)
+
+	inputMap := utils.PrimitivePathMap{"foo.example.com": {"/foo1": "foo1svc"}}
+	ing := newIngress(inputMap)
+	cert := extensions.IngressTLS{SecretName: "foo-tls"}
+	ing.Spec.TLS = []extensions.IngressTLS{cert}
+	ing.Annotations = map[string]string{
+		annotations.PassTLSCertKey:               "true",
+		annotations.ClientCertTrustSecretNameKey: "foo-ca",
+	}
+	lbc.tlsLoader = &tls.FakeTLSSecretLoader{
+		FakeCerts: map[string]*loadbalancers.TLSCerts{
+			cert.SecretName: {Key: "foo", Cert: "bar"},
+		},
+		FakeCACerts: map[string]string{
+			"foo-ca": "-----BEGIN CERTIFICATE-----\nfakeca\n-----END CERTIFICATE-----",
+		},
+	}
+
+	addIngress(lbc, ing, pm)
+	ingStoreKey := getKey(ing, t)
+	if err := lbc.sync(ingStoreKey); err != nil {
+		t.Fatalf("lbc.sync(%v) = %v, want nil", ingStoreKey, err)
+	}
+
+	httpsProxyName := cm.Namer.TargetProxy(ingStoreKey, "https")
+	policyName, ok := cm.fakeLbs.ProxyServerTLSPolicy[httpsProxyName]
+	if !ok {
+		t.Fatalf("cm.fakeLbs.ProxyServerTLSPolicy[%q] missing, want a ServerTLSPolicy attached", httpsProxyName)
+	}
+	policy, ok := cm.fakeLbs.ServerTLSPolicies[policyName]
+	if !ok {
+		t.Fatalf("cm.fakeLbs.ServerTLSPolicies[%q] missing", policyName)
+	}
+	if policy.ClientValidationCA != lbc.tlsLoader.(*tls.FakeTLSSecretLoader).FakeCACerts["foo-ca"] {
+		t.Errorf("policy.ClientValidationCA = %q, want the loaded CA bundle", policy.ClientValidationCA)
+	}
+	if policy.ClientCertHeaderName != annotations.DefaultPassTLSHeaderName {
+		t.Errorf("policy.ClientCertHeaderName = %q, want %q", policy.ClientCertHeaderName, annotations.DefaultPassTLSHeaderName)
+	}
+}
+
+// TestLbClientCertPassThroughWithoutTrustSecretIsANoOp asserts that the
+// pass-tls-cert annotation alone, without a client-cert-trust Secret to
+// validate against, never attaches a ServerTLSPolicy -- there's no CA to
+// configure GCE with.
+func TestLbClientCertPassThroughWithoutTrustSecretIsANoOp(t *testing.T) {
+	cm := NewFakeClusterManager(flags.DefaultClusterUID, DefaultFirewallName)
+	lbc := newLoadBalancerController(t, cm)
+	pm := newPortManager(1, 65536, cm.Namer)
+
+	inputMap := utils.PrimitivePathMap{"foo.example.com": {"/foo1": "foo1svc"}}
+	ing := newIngress(inputMap)
+	cert := extensions.IngressTLS{SecretName: "foo-tls"}
+	ing.Spec.TLS = []extensions.IngressTLS{cert}
+	ing.Annotations = map[string]string{annotations.PassTLSCertKey: "true"}
+	lbc.tlsLoader = &tls.FakeTLSSecretLoader{
+		FakeCerts: map[string]*loadbalancers.TLSCerts{
+			cert.SecretName: {Key: "foo", Cert: "bar"},
+		},
+	}
+
+	addIngress(lbc, ing, pm)
+	ingStoreKey := getKey(ing, t)
+	if err := lbc.sync(ingStoreKey); err != nil {
+		t.Fatalf("lbc.sync(%v) = %v, want nil", ingStoreKey, err)
+	}
+
+	httpsProxyName := cm.Namer.TargetProxy(ingStoreKey, "https")
+	if policyName, ok := cm.fakeLbs.ProxyServerTLSPolicy[httpsProxyName]; ok {
+		t.Errorf("cm.fakeLbs.ProxyServerTLSPolicy[%q] = %q, want none without a trusted CA", httpsProxyName, policyName)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2015 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/ingress-gce/pkg/backends"
+	"k8s.io/ingress-gce/pkg/firewalls"
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// ClusterManager wraps the pools LoadBalancerController drives to create
+// and garbage collect a cluster's GCE load balancing resources: one L7 per
+// Ingress, the BackendServices they share, and the cluster's single
+// firewall rule.
+type ClusterManager struct {
+	// Namer names every GCE resource the pools below create.
+	Namer *utils.Namer
+
+	l7Pool       *loadbalancers.L7Pool
+	backendPool  *backends.Pool
+	firewallPool firewalls.SingleFirewallPool
+	refs         *backends.RefCountIndex
+}
+
+// GetL7 returns the L7 load balancer for an Ingress store key, or an error
+// if LoadBalancerController hasn't synced that Ingress yet. It exists for
+// test harnesses built outside this package (e.g. pkg/controller/testing)
+// that need to inspect the resources a sync produced.
+func (cm *ClusterManager) GetL7(key string) (*loadbalancers.L7, error) {
+	return cm.l7Pool.Get(key)
+}
+
+// BackendExists reports whether nodePort currently has a BackendService.
+// It exists for test harnesses built outside this package that need to
+// confirm a URL map's backend wasn't garbage collected out from under it.
+func (cm *ClusterManager) BackendExists(nodePort int64) bool {
+	_, err := cm.backendPool.Get(cm.Namer.IGBackend(nodePort), false)
+	return err == nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"testing"
+
+	"k8s.io/ingress-gce/pkg/flags"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// TestConcurrentSyncNeverDropsSharedBackendWhileReferenced interleaves
+// repeated syncs of two Ingresses that share a backend Service: ingA drops
+// its /foo2 rule (and foo2svc, which only it depends on), while ingB keeps
+// resyncing against sharedsvc throughout. sharedsvc's backend must survive
+// the whole run, since ingB never stops depending on it; foo2svc's backend
+// must end up GC'd, since ingA no longer does.
+func TestConcurrentSyncNeverDropsSharedBackendWhileReferenced(t *testing.T) {
+	cm := NewFakeClusterManager(flags.DefaultClusterUID, DefaultFirewallName)
+	lbc := newLoadBalancerController(t, cm)
+	pm := newPortManager(1, 65536, cm.Namer)
+
+	ingA := newIngress(utils.PrimitivePathMap{
+		"a.example.com": {"/foo1": "sharedsvc", "/foo2": "foo2svc"},
+	})
+	ingB := newIngress(utils.PrimitivePathMap{
+		"b.example.com": {"/bar1": "sharedsvc"},
+	})
+	addIngress(lbc, ingA, pm)
+	addIngress(lbc, ingB, pm)
+	keyA, keyB := getKey(ingA, t), getKey(ingB, t)
+
+	if err := lbc.sync(keyA); err != nil {
+		t.Fatalf("lbc.sync(keyA) = %v, want nil", err)
+	}
+	if err := lbc.sync(keyB); err != nil {
+		t.Fatalf("lbc.sync(keyB) = %v, want nil", err)
+	}
+
+	sharedBeName := cm.Namer.IGBackend(int64(pm.getNodePort("sharedsvc")))
+	if _, err := cm.backendPool.Get(sharedBeName, false); err != nil {
+		t.Fatalf("before concurrent syncs: cm.backendPool.Get(%q) = %v, want nil", sharedBeName, err)
+	}
+
+	// Drop ingA's /foo2 rule in place, so its store key doesn't change.
+	ingA.Spec.Rules = toIngressRules(utils.PrimitivePathMap{"a.example.com": {"/foo1": "sharedsvc"}})
+	if err := lbc.ctx.IngressInformer.GetIndexer().Update(ingA); err != nil {
+		t.Fatalf("IngressInformer.GetIndexer().Update(ingA) = %v, want nil", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := lbc.sync(keyA); err != nil {
+				errs <- err
+				return
+			}
+			if _, err := cm.backendPool.Get(sharedBeName, false); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := lbc.sync(keyB); err != nil {
+				errs <- err
+				return
+			}
+			if _, err := cm.backendPool.Get(sharedBeName, false); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent sync of sharedsvc's dependents = %v, want the backend to survive throughout", err)
+	}
+
+	refs := cm.backendPool.Refs(int64(pm.getNodePort("sharedsvc")))
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		seen[ref] = true
+	}
+	if len(refs) != 2 || !seen[keyA] || !seen[keyB] {
+		t.Errorf("cm.backendPool.Refs(sharedsvc) = %v, want exactly [%v %v] since ingA's /foo1 and ingB's /bar1 both still depend on it", refs, keyA, keyB)
+	}
+
+	foo2BeName := cm.Namer.IGBackend(int64(pm.getNodePort("foo2svc")))
+	if be, err := cm.backendPool.Get(foo2BeName, false); err == nil {
+		t.Errorf("after concurrent syncs: found backend %+v for foo2svc, want it GC'd once ingA dropped /foo2", be)
+	}
+}
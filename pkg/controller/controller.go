@@ -0,0 +1,423 @@
+/*
+Copyright 2015 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controller implements LoadBalancerController, which watches
+// Ingress/Service/Secret objects and drives a ClusterManager to keep GCE's
+// load balancing resources in sync with them.
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	api_v1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	listers_v1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"k8s.io/ingress-gce/pkg/annotations"
+	"k8s.io/ingress-gce/pkg/context"
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+	"k8s.io/ingress-gce/pkg/tls"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// keyFunc derives an Ingress's store key (namespace/name) from it, or from
+// a cache.DeletedFinalStateUnknown tombstone if it's already been deleted.
+var keyFunc = cache.DeletionHandlingMetaNamespaceKeyFunc
+
+// defaultBackendRefName is the pseudo Service name updateBackendRefs
+// registers an Ingress's default-backend node port under in the
+// RefCountIndex. It isn't a real Service: an Ingress's default backend
+// falls back to lbc.defaultBackendNodePort whenever Spec.Backend is nil or
+// unresolved, and that fallback node port needs a ref of its own so
+// backendPool.GC doesn't delete it out from under the URL map that still
+// points at it.
+const defaultBackendRefName = "<default-backend>"
+
+// ingressLister wraps the Ingress store LoadBalancerController watches,
+// giving tests direct access to mutate it (e.g. simulating a delete that
+// hasn't reached the controller's queue yet).
+type ingressLister struct {
+	Store cache.Store
+}
+
+// List returns every Ingress currently in the store.
+func (l *ingressLister) List() []*extensions.Ingress {
+	var out []*extensions.Ingress
+	for _, obj := range l.Store.List() {
+		out = append(out, obj.(*extensions.Ingress))
+	}
+	return out
+}
+
+// LoadBalancerController watches Ingress, Service, and Secret objects and
+// syncs each Ingress's desired routing/TLS state to a ClusterManager.
+type LoadBalancerController struct {
+	client kubernetes.Interface
+	ctx    *context.ControllerContext
+	cm     *ClusterManager
+
+	ingLister ingressLister
+	svcLister listers_v1.ServiceLister
+
+	tlsLoader tls.TLSSecretLoader
+
+	queue workqueue.RateLimitingInterface
+
+	// hasSynced reports whether every watched informer has completed its
+	// initial list. Tests stub it to report true without waiting on a real
+	// informer's ListAndWatch.
+	hasSynced func() bool
+
+	defaultBackendNodePort int64
+	// enableNEG is reserved for a future Network Endpoint Group backend
+	// mode; nothing reads it yet.
+	enableNEG bool
+
+	stopCh chan struct{}
+}
+
+// NewLoadBalancerController returns a LoadBalancerController watching
+// kubeClient's Ingress/Service/Secret informers in ctx, driving cm.
+// defaultBackendNodePort is used for any Ingress whose default backend
+// Service can't be resolved via the Service lister.
+func NewLoadBalancerController(kubeClient kubernetes.Interface, stopCh chan struct{}, ctx *context.ControllerContext, cm *ClusterManager, enableNEG bool, defaultBackendNodePort int64) (*LoadBalancerController, error) {
+	lbc := &LoadBalancerController{
+		client:                 kubeClient,
+		ctx:                    ctx,
+		cm:                     cm,
+		ingLister:              ingressLister{Store: ctx.IngressInformer.GetIndexer()},
+		svcLister:              listers_v1.NewServiceLister(ctx.ServiceInformer.GetIndexer()),
+		tlsLoader:              tls.NewSecretTLSLoader(listers_v1.NewSecretLister(ctx.SecretInformer.GetIndexer())),
+		queue:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		hasSynced:              ctx.HasSynced,
+		defaultBackendNodePort: defaultBackendNodePort,
+		enableNEG:              enableNEG,
+		stopCh:                 stopCh,
+	}
+
+	ctx.IngressInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    lbc.enqueueIngress,
+		UpdateFunc: func(old, cur interface{}) { lbc.enqueueIngress(cur) },
+		DeleteFunc: lbc.enqueueIngress,
+	})
+	ctx.ServiceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    lbc.enqueueIngressForService,
+		UpdateFunc: func(old, cur interface{}) { lbc.enqueueIngressForService(cur) },
+	})
+
+	return lbc, nil
+}
+
+// enqueueIngress queues obj's store key for sync.
+func (lbc *LoadBalancerController) enqueueIngress(obj interface{}) {
+	key, err := keyFunc(obj)
+	if err != nil {
+		return
+	}
+	lbc.queue.Add(key)
+}
+
+// enqueueIngressForService queues every Ingress that depends on obj, a
+// Service, so that an Ingress synced before its backend Services existed
+// gets a chance to pick up their node ports once they show up.
+func (lbc *LoadBalancerController) enqueueIngressForService(obj interface{}) {
+	svc, ok := obj.(*api_v1.Service)
+	if !ok {
+		return
+	}
+	for _, ing := range lbc.ingLister.List() {
+		if ing.Namespace != svc.Namespace {
+			continue
+		}
+		for _, name := range backendServiceNames(ing) {
+			if name == svc.Name {
+				lbc.enqueueIngress(ing)
+				break
+			}
+		}
+	}
+}
+
+// Run starts every informer and processes the sync queue until stopCh
+// closes.
+func (lbc *LoadBalancerController) Run() {
+	defer lbc.queue.ShutDown()
+
+	lbc.ctx.Start(lbc.stopCh)
+	if !cache.WaitForCacheSync(lbc.stopCh, lbc.hasSynced) {
+		return
+	}
+
+	lbc.rebuildBackendRefs()
+
+	go wait.Until(lbc.worker, time.Second, lbc.stopCh)
+	<-lbc.stopCh
+}
+
+// rebuildBackendRefs repopulates cm.refs from every Ingress already in the
+// lister. It runs once, after the informer caches have synced and before
+// the worker loop starts processing queue items, so a controller that
+// crashed and restarted doesn't resume with every backend's refcount at
+// zero -- which could let backendPool.GC prune a BackendService an
+// existing Ingress still depends on before that Ingress gets a chance to
+// resync and re-register it.
+func (lbc *LoadBalancerController) rebuildBackendRefs() {
+	lbc.cm.refs.Rebuild(func(add func(nodePort int64, svcName, ingKey string)) {
+		for _, ing := range lbc.ingLister.List() {
+			key, err := keyFunc(ing)
+			if err != nil {
+				continue
+			}
+			for _, svcName := range backendServiceNames(ing) {
+				nodePort, err := lbc.serviceNodePort(ing.Namespace, svcName)
+				if err != nil {
+					continue
+				}
+				add(nodePort, svcName, key)
+			}
+			add(lbc.resolveDefaultBackend(ing), defaultBackendRefName, key)
+		}
+	})
+}
+
+func (lbc *LoadBalancerController) worker() {
+	for lbc.processNextItem() {
+	}
+}
+
+func (lbc *LoadBalancerController) processNextItem() bool {
+	key, quit := lbc.queue.Get()
+	if quit {
+		return false
+	}
+	defer lbc.queue.Done(key)
+
+	if err := lbc.sync(key.(string)); err != nil {
+		lbc.queue.AddRateLimited(key)
+		return true
+	}
+	lbc.queue.Forget(key)
+	return true
+}
+
+// Sync exposes sync to callers outside this package (e.g. the conformance
+// harness in pkg/controller/testing) that want a synchronous reconcile
+// without running the full worker queue loop.
+func (lbc *LoadBalancerController) Sync(key string) error {
+	return lbc.sync(key)
+}
+
+// sync reconciles the Ingress named by key with the cluster's GCE
+// resources: it's the entrypoint both the workqueue and tests drive.
+func (lbc *LoadBalancerController) sync(key string) error {
+	obj, exists, err := lbc.ingLister.Store.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return lbc.gcIngress(key)
+	}
+	ing := obj.(*extensions.Ingress)
+	ann := annotations.IngressAnnotations(ing.Annotations)
+
+	lbc.updateBackendRefs(key, ing)
+
+	tlsCerts, err := lbc.ingressTLSCerts(ing)
+	if err != nil {
+		return err
+	}
+
+	var trustedCA string
+	if secretName := ann.ClientCertTrustSecretName(); secretName != "" {
+		trustedCA, err = lbc.tlsLoader.GetCACertFromSecret(ing.Namespace, secretName)
+		if err != nil {
+			return err
+		}
+	}
+
+	info := &loadbalancers.L7RuntimeInfo{
+		Name:          key,
+		UrlMap:        lbc.translateIngress(ing),
+		TLS:           tlsCerts,
+		StaticIPName:  ann.StaticIPName(),
+		ExistingIP:    existingIngressIP(ing),
+		Ingress:       ann,
+		TrustedCACert: trustedCA,
+	}
+
+	l7, err := lbc.cm.l7Pool.Ensure(info)
+	if err != nil {
+		return err
+	}
+
+	if err := lbc.updateIngressStatus(ing, l7); err != nil {
+		return err
+	}
+
+	if err := lbc.cm.backendPool.GC(); err != nil {
+		return err
+	}
+	return lbc.syncFirewall()
+}
+
+// translateIngress builds the GCEURLMap describing ing's desired routing
+// state, resolving each rule's Service backend to a node port via the
+// Service lister. A rule whose backend Service hasn't shown up in the
+// lister yet is dropped rather than failing the whole sync; it's picked up
+// on the resync enqueueIngressForService triggers once the Service exists.
+func (lbc *LoadBalancerController) translateIngress(ing *extensions.Ingress) *utils.GCEURLMap {
+	urlMap := utils.NewGCEURLMap()
+	urlMap.DefaultBackend = lbc.resolveDefaultBackend(ing)
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		host := rule.Host
+		if host == "" {
+			host = loadbalancers.DefaultHost
+		}
+
+		var pathRules []utils.PathRule
+		for _, p := range rule.HTTP.Paths {
+			nodePort, err := lbc.serviceNodePort(ing.Namespace, p.Backend.ServiceName)
+			if err != nil {
+				continue
+			}
+			path := p.Path
+			if path == "" {
+				path = loadbalancers.DefaultPath
+			}
+			pathRules = append(pathRules, utils.PathRule{Path: path, Backend: utils.ServicePort{NodePort: nodePort}})
+		}
+		urlMap.PutPathRulesForHost(host, pathRules)
+	}
+	return urlMap
+}
+
+// resolveDefaultBackend returns the node port ing's default backend should
+// route to: the resolved node port of Spec.Backend's Service if it's set
+// and resolvable, or lbc.defaultBackendNodePort otherwise.
+func (lbc *LoadBalancerController) resolveDefaultBackend(ing *extensions.Ingress) int64 {
+	if ing.Spec.Backend != nil {
+		if nodePort, err := lbc.serviceNodePort(ing.Namespace, ing.Spec.Backend.ServiceName); err == nil {
+			return nodePort
+		}
+	}
+	return lbc.defaultBackendNodePort
+}
+
+// serviceNodePort resolves svcName's node port via the Service lister.
+func (lbc *LoadBalancerController) serviceNodePort(namespace, svcName string) (int64, error) {
+	svc, err := lbc.svcLister.Services(namespace).Get(svcName)
+	if err != nil {
+		return 0, err
+	}
+	if len(svc.Spec.Ports) == 0 {
+		return 0, fmt.Errorf("service %v/%v has no ports", namespace, svcName)
+	}
+	return int64(svc.Spec.Ports[0].NodePort), nil
+}
+
+// updateBackendRefs re-registers ing's backend dependencies in the
+// cluster's RefCountIndex, dropping whatever it had registered before --
+// the simplest way to keep the index in step with an Ingress whose rules
+// changed is to rebuild its entry from scratch on every sync.
+func (lbc *LoadBalancerController) updateBackendRefs(key string, ing *extensions.Ingress) {
+	lbc.cm.refs.RemoveIngress(key)
+	for _, svcName := range backendServiceNames(ing) {
+		nodePort, err := lbc.serviceNodePort(ing.Namespace, svcName)
+		if err != nil {
+			continue
+		}
+		lbc.cm.refs.Add(nodePort, svcName, key)
+	}
+	// translateIngress always points the URL map's default backend at
+	// resolveDefaultBackend's node port, including the fallback case where
+	// it isn't fronted by a resolvable Service -- register it under
+	// defaultBackendRefName so it gets the same GC protection.
+	lbc.cm.refs.Add(lbc.resolveDefaultBackend(ing), defaultBackendRefName, key)
+}
+
+// ingressTLSCerts loads the serving certificate/key pair for every Secret
+// ing's TLS spec references.
+func (lbc *LoadBalancerController) ingressTLSCerts(ing *extensions.Ingress) ([]*loadbalancers.TLSCerts, error) {
+	var out []*loadbalancers.TLSCerts
+	for _, t := range ing.Spec.TLS {
+		cert, err := lbc.tlsLoader.GetTLSCertFromSecret(ing.Namespace, t.SecretName)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cert)
+	}
+	return out, nil
+}
+
+// existingIngressIP returns the IP ing's status already reports, or "" if
+// it hasn't been assigned one yet.
+func existingIngressIP(ing *extensions.Ingress) string {
+	if len(ing.Status.LoadBalancer.Ingress) == 0 {
+		return ""
+	}
+	return ing.Status.LoadBalancer.Ingress[0].IP
+}
+
+// updateIngressStatus writes l7's resolved IP back to ing's status, if it
+// differs from what's already there.
+func (lbc *LoadBalancerController) updateIngressStatus(ing *extensions.Ingress, l7 *loadbalancers.L7) error {
+	ip := l7.IP()
+	if len(ing.Status.LoadBalancer.Ingress) == 1 && ing.Status.LoadBalancer.Ingress[0].IP == ip {
+		return nil
+	}
+	update := ing.DeepCopy()
+	update.Status.LoadBalancer.Ingress = []api_v1.LoadBalancerIngress{{IP: ip}}
+	_, err := lbc.client.Extensions().Ingresses(update.Namespace).UpdateStatus(update)
+	return err
+}
+
+// gcIngress tears down the GCE resources for an Ingress that's no longer
+// in the store, and reconciles the firewall/backend pools against whatever
+// remains.
+func (lbc *LoadBalancerController) gcIngress(key string) error {
+	lbc.cm.refs.RemoveIngress(key)
+
+	if err := lbc.cm.l7Pool.Delete(key); err != nil {
+		return err
+	}
+	if err := lbc.cm.backendPool.GC(); err != nil {
+		return err
+	}
+	return lbc.syncFirewall()
+}
+
+// syncFirewall keeps the cluster's single firewall rule open on exactly
+// the node ports still in use, tearing it down once no Ingress remains.
+func (lbc *LoadBalancerController) syncFirewall() error {
+	if !lbc.ingressesRemain() {
+		return lbc.cm.firewallPool.Shutdown()
+	}
+	return lbc.cm.firewallPool.EnsureFirewall(lbc.cm.backendPool.NodePorts())
+}
+
+// ingressesRemain reports whether any Ingress is still in the store.
+func (lbc *LoadBalancerController) ingressesRemain() bool {
+	return len(lbc.ingLister.List()) > 0
+}
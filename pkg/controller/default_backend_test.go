@@ -0,0 +1,61 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/ingress-gce/pkg/flags"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// TestDefaultBackendSurvivesGC guards against the fallback default backend
+// (the node port translateIngress uses when an Ingress's Spec.Backend
+// Service never resolves, as in every test Ingress here) getting created
+// and then immediately garbage collected within the same sync -- it must
+// still be there, and still be in the firewall's allowed port set, after
+// every sync the URL map depends on it.
+func TestDefaultBackendSurvivesGC(t *testing.T) {
+	cm := NewFakeClusterManager(flags.DefaultClusterUID, DefaultFirewallName)
+	lbc := newLoadBalancerController(t, cm)
+	pm := newPortManager(1, 65536, cm.Namer)
+
+	ing := newIngress(utils.PrimitivePathMap{"foo.example.com": {"/foo1": "foo1svc"}})
+	addIngress(lbc, ing, pm)
+	ingStoreKey := getKey(ing, t)
+
+	for i := 0; i < 2; i++ {
+		if err := lbc.sync(ingStoreKey); err != nil {
+			t.Fatalf("lbc.sync(%v) = %v, want nil", ingStoreKey, err)
+		}
+
+		beName := cm.Namer.IGBackend(testDefaultBeNodePort)
+		if _, err := cm.backendPool.Get(beName, false); err != nil {
+			t.Fatalf("after sync %d: cm.backendPool.Get(%q) = %v, want the default backend to survive GC", i, beName, err)
+		}
+
+		foundDefaultPort := false
+		for _, port := range cm.backendPool.NodePorts() {
+			if port == testDefaultBeNodePort {
+				foundDefaultPort = true
+			}
+		}
+		if !foundDefaultPort {
+			t.Fatalf("after sync %d: cm.backendPool.NodePorts() = %v, want it to include the default backend's node port %d (so the firewall opens it)", i, cm.backendPool.NodePorts(), testDefaultBeNodePort)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2015 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-gce/pkg/backends"
+	"k8s.io/ingress-gce/pkg/firewalls"
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+const (
+	// testDefaultBeNodePort is the node port tests configure as the
+	// cluster's default backend, standing in for the defaultBackendName
+	// Service fixture that's never actually added to the Service store.
+	testDefaultBeNodePort = int64(30000)
+
+	// DefaultFirewallName is the firewall rule name tests use when they
+	// don't care to override it.
+	DefaultFirewallName = "k8s-fw-default"
+)
+
+// testBackendPort is the ServicePort every test Ingress rule references.
+var testBackendPort = intstr.FromInt(80)
+
+// fakeClusterManager is a ClusterManager backed entirely by in-memory
+// fakes, for use in tests.
+type fakeClusterManager struct {
+	*ClusterManager
+	fakeLbs *loadbalancers.FakeLoadBalancers
+}
+
+// NewFakeClusterManager returns a fakeClusterManager for a cluster named
+// clusterUID whose firewall rule is initially named firewallName.
+func NewFakeClusterManager(clusterUID, firewallName string) *fakeClusterManager {
+	namer := utils.NewNamer(clusterUID, firewallName)
+	fakeLbs := loadbalancers.NewFakeLoadBalancers()
+	refs := backends.NewRefCountIndex()
+	backendPool := backends.NewPool(namer, refs)
+	l7Pool := loadbalancers.NewL7Pool(fakeLbs, namer, backendLinkForNodePort(backendPool))
+	firewallPool := firewalls.NewFirewallPool(namer)
+
+	return &fakeClusterManager{
+		ClusterManager: &ClusterManager{
+			Namer:        namer,
+			l7Pool:       l7Pool,
+			backendPool:  backendPool,
+			firewallPool: firewallPool,
+			refs:         refs,
+		},
+		fakeLbs: fakeLbs,
+	}
+}
+
+// FakeLoadBalancers returns the in-memory FakeLoadBalancers backing cm, for
+// test harnesses built outside this package that need to inspect pushed
+// GCE state (UrlMaps, forwarding rules, ...) directly.
+func (cm *fakeClusterManager) FakeLoadBalancers() *loadbalancers.FakeLoadBalancers {
+	return cm.fakeLbs
+}
+
+// backendLinkForNodePort resolves a node port to the resource name of the
+// BackendService backendPool ensures fronts it, creating it if this is the
+// first L7 to reference that node port. There's no real resource link to
+// return in this fake world, so the BackendService's own name stands in
+// for it.
+func backendLinkForNodePort(backendPool *backends.Pool) func(nodePort int64) string {
+	return func(nodePort int64) string {
+		be, err := backendPool.Ensure(nodePort)
+		if err != nil {
+			return ""
+		}
+		return be.Name
+	}
+}
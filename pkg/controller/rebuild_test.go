@@ -0,0 +1,69 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-gce/pkg/flags"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// TestRebuildBackendRefsRecoversFromRestart simulates a controller restart:
+// the refcount index is a fresh in-memory structure (as it would be after a
+// crash), but the Ingress/Service stores already reflect everything the API
+// server knows about (as a relist would produce). rebuildBackendRefs must
+// repopulate refs to exactly what the live Ingresses depend on, discarding
+// any stale entries left over from before the restart.
+func TestRebuildBackendRefsRecoversFromRestart(t *testing.T) {
+	cm := NewFakeClusterManager(flags.DefaultClusterUID, DefaultFirewallName)
+	lbc := newLoadBalancerController(t, cm)
+	pm := newPortManager(1, 65536, cm.Namer)
+
+	ing1 := newIngress(utils.PrimitivePathMap{"foo.example.com": {"/foo1": "sharedsvc"}})
+	ing2 := newIngress(utils.PrimitivePathMap{"bar.example.com": {"/bar1": "sharedsvc"}})
+	addIngress(lbc, ing1, pm)
+	addIngress(lbc, ing2, pm)
+
+	for _, ing := range []*extensions.Ingress{ing1, ing2} {
+		if err := lbc.sync(getKey(ing, t)); err != nil {
+			t.Fatalf("lbc.sync(%v) = %v, want nil", getKey(ing, t), err)
+		}
+	}
+
+	nodePort := int64(pm.getNodePort("sharedsvc"))
+	if got := cm.refs.Count(nodePort, "sharedsvc"); got != 2 {
+		t.Fatalf("before restart: cm.refs.Count(%d, sharedsvc) = %d, want 2", nodePort, got)
+	}
+
+	// Simulate leftover state from before the crash: a dependency from an
+	// Ingress that no longer exists in the lister.
+	cm.refs.Add(nodePort, "sharedsvc", "default/ghost-ingress")
+
+	lbc.rebuildBackendRefs()
+
+	if got := cm.refs.Count(nodePort, "sharedsvc"); got != 2 {
+		t.Errorf("after rebuildBackendRefs: cm.refs.Count(%d, sharedsvc) = %d, want 2", nodePort, got)
+	}
+	for _, ingKey := range cm.refs.Refs(nodePort) {
+		if ingKey == "default/ghost-ingress" {
+			t.Errorf("after rebuildBackendRefs: cm.refs.Refs(%d) still contains stale ghost-ingress", nodePort)
+		}
+	}
+}
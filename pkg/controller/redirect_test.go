@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-gce/pkg/annotations"
+	"k8s.io/ingress-gce/pkg/flags"
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+	"k8s.io/ingress-gce/pkg/tls"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// TestLbForceSSLRedirect is in the style of TestLbChangeStaticIP: it drives
+// an Ingress carrying the force-ssl-redirect annotation through a real
+// sync and asserts on the fake LB's resulting proxy/UrlMap state, rather
+// than calling shouldRedirectToHTTPS/redirectUrlMapFromGCEURLMap directly
+// the way pkg/loadbalancers/redirect_test.go does.
+func TestLbForceSSLRedirect(t *testing.T) {
+	cm := NewFakeClusterManager(flags.DefaultClusterUID, DefaultFirewallName)
+	lbc := newLoadBalancerController(t, cm)
+	pm := newPortManager(1, 65536, cm.Namer)
+
+	inputMap := utils.PrimitivePathMap{"foo.example.com": {"/foo1": "foo1svc"}}
+	ing := newIngress(inputMap)
+	ing.Annotations = map[string]string{annotations.SSLRedirectKey: "true"}
+	cert := extensions.IngressTLS{SecretName: "foo-tls"}
+	ing.Spec.TLS = []extensions.IngressTLS{cert}
+	lbc.tlsLoader = &tls.FakeTLSSecretLoader{
+		FakeCerts: map[string]*loadbalancers.TLSCerts{
+			cert.SecretName: {Key: "foo", Cert: "bar"},
+		},
+	}
+
+	addIngress(lbc, ing, pm)
+	ingStoreKey := getKey(ing, t)
+	if err := lbc.sync(ingStoreKey); err != nil {
+		t.Fatalf("lbc.sync(%v) = %v, want nil", ingStoreKey, err)
+	}
+
+	urlMapName := cm.Namer.UrlMap(ingStoreKey)
+	redirectName := urlMapName + "-redirect"
+	if _, ok := cm.fakeLbs.Um[redirectName]; !ok {
+		t.Fatalf("cm.fakeLbs.Um[%q] missing, want a redirect-only UrlMap", redirectName)
+	}
+
+	httpProxyName := cm.Namer.TargetProxy(ingStoreKey, "http")
+	httpProxy, ok := cm.fakeLbs.Tp[httpProxyName]
+	if !ok {
+		t.Fatalf("cm.fakeLbs.Tp[%q] missing", httpProxyName)
+	}
+	if httpProxy.UrlMap != redirectName {
+		t.Errorf("http target proxy UrlMap = %q, want %q", httpProxy.UrlMap, redirectName)
+	}
+
+	httpsProxyName := cm.Namer.TargetProxy(ingStoreKey, "https")
+	httpsProxy, ok := cm.fakeLbs.Tps[httpsProxyName]
+	if !ok {
+		t.Fatalf("cm.fakeLbs.Tps[%q] missing", httpsProxyName)
+	}
+	if httpsProxy.UrlMap != urlMapName {
+		t.Errorf("https target proxy UrlMap = %q, want the regular UrlMap %q, not the redirect one", httpsProxy.UrlMap, urlMapName)
+	}
+}
+
+// TestLbNoForceSSLRedirectWithoutTLS asserts that the force-ssl-redirect
+// annotation is a no-op without a TLS cert or static IP to redirect to:
+// redirecting to HTTPS would just break the Ingress.
+func TestLbNoForceSSLRedirectWithoutTLS(t *testing.T) {
+	cm := NewFakeClusterManager(flags.DefaultClusterUID, DefaultFirewallName)
+	lbc := newLoadBalancerController(t, cm)
+	pm := newPortManager(1, 65536, cm.Namer)
+
+	inputMap := utils.PrimitivePathMap{"foo.example.com": {"/foo1": "foo1svc"}}
+	ing := newIngress(inputMap)
+	ing.Annotations = map[string]string{annotations.SSLRedirectKey: "true"}
+	addIngress(lbc, ing, pm)
+	ingStoreKey := getKey(ing, t)
+	if err := lbc.sync(ingStoreKey); err != nil {
+		t.Fatalf("lbc.sync(%v) = %v, want nil", ingStoreKey, err)
+	}
+
+	httpProxyName := cm.Namer.TargetProxy(ingStoreKey, "http")
+	httpProxy, ok := cm.fakeLbs.Tp[httpProxyName]
+	if !ok {
+		t.Fatalf("cm.fakeLbs.Tp[%q] missing", httpProxyName)
+	}
+	urlMapName := cm.Namer.UrlMap(ingStoreKey)
+	if httpProxy.UrlMap != urlMapName {
+		t.Errorf("http target proxy UrlMap = %q, want the regular UrlMap %q (no TLS, so no redirect)", httpProxy.UrlMap, urlMapName)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+// backendServiceNames returns the distinct Service names ing's default
+// backend and rules depend on. LoadBalancerController calls this on every
+// sync of an Ingress still in the store, and for each Ingress when it
+// rebuilds its backends.RefCountIndex on start, to know which
+// (nodePort, svcName) pairs to register; a deleted Ingress's pairs are
+// released by key via RemoveIngress instead. Resolving a
+// Service name to the node port the index is actually keyed by is left to
+// the caller, since that requires the Service lister the controller
+// already holds.
+func backendServiceNames(ing *extensions.Ingress) []string {
+	seen := map[string]bool{}
+	var names []string
+	add := func(name string) {
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	if ing.Spec.Backend != nil {
+		add(ing.Spec.Backend.ServiceName)
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			add(p.Backend.ServiceName)
+		}
+	}
+	return names
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+func TestBackendServiceNames(t *testing.T) {
+	ing := newIngress(utils.PrimitivePathMap{
+		"foo.example.com": {
+			"/foo1": "foo1svc",
+			"/foo2": "foo2svc",
+		},
+		"bar.example.com": {
+			"/bar1": "foo1svc", // shared with foo.example.com/foo1
+		},
+	})
+
+	got := backendServiceNames(ing)
+	sort.Strings(got)
+
+	want := []string{defaultBackendName(testClusterName), "foo1svc", "foo2svc"}
+	sort.Strings(want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("backendServiceNames(ing) = %v, want %v", got, want)
+	}
+}
+
+func TestBackendServiceNamesSkipsEmptyHTTPRule(t *testing.T) {
+	ing := newIngress(utils.PrimitivePathMap{})
+	ing.Spec.Backend = nil
+	ing.Spec.Rules = []extensions.IngressRule{{Host: "foo.example.com"}}
+
+	if got := backendServiceNames(ing); len(got) != 0 {
+		t.Fatalf("backendServiceNames(ing) = %v, want empty", got)
+	}
+}
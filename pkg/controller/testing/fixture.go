@@ -0,0 +1,228 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testing provides ControllerFixture, an e2e conformance harness
+// for LoadBalancerController: it drives a real controller and ClusterManager
+// against a replayable, in-memory GCE fake, so a caller can Apply an actual
+// Ingress and assert on the UrlMap/forwarding rules the controller actually
+// produced, rather than hand-rolling a ClusterManager per test the way
+// pkg/controller's own tests do.
+package testing
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	api_v1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-gce/pkg/context"
+	"k8s.io/ingress-gce/pkg/controller"
+	"k8s.io/ingress-gce/pkg/flags"
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// fixtureDefaultBackendNodePort is the node port this fixture's
+// LoadBalancerController falls back to for an Ingress whose default
+// backend Service was never registered via RegisterService.
+const fixtureDefaultBackendNodePort = int64(30000)
+
+// ControllerFixture wraps a real LoadBalancerController and ClusterManager,
+// backed entirely by in-memory fakes, so tests can Apply an Ingress and
+// assert on the GCE state it produced.
+type ControllerFixture struct {
+	ctx     *context.ControllerContext
+	lbc     *controller.LoadBalancerController
+	cm      *controller.ClusterManager
+	fakeLbs *loadbalancers.FakeLoadBalancers
+
+	stopCh chan struct{}
+
+	namespace string
+	key       string
+}
+
+// NewControllerFixture returns a ControllerFixture with no Ingresses or
+// Services yet.
+func NewControllerFixture() (*ControllerFixture, error) {
+	kubeClient := fake.NewSimpleClientset()
+	ctx := context.NewControllerContext(kubeClient, api_v1.NamespaceAll, time.Second, true)
+	cm := controller.NewFakeClusterManager(flags.DefaultClusterUID, controller.DefaultFirewallName)
+	stopCh := make(chan struct{})
+
+	lbc, err := controller.NewLoadBalancerController(kubeClient, stopCh, ctx, cm.ClusterManager, true, fixtureDefaultBackendNodePort)
+	if err != nil {
+		return nil, fmt.Errorf("NewLoadBalancerController() = %v", err)
+	}
+
+	return &ControllerFixture{
+		ctx:     ctx,
+		lbc:     lbc,
+		cm:      cm.ClusterManager,
+		fakeLbs: cm.FakeLoadBalancers(),
+		stopCh:  stopCh,
+	}, nil
+}
+
+// RegisterService adds a Service named name in namespace, with the given
+// node port, as if the API server already had it -- so that an Ingress
+// rule referencing it resolves on the very next Apply.
+func (f *ControllerFixture) RegisterService(namespace, name string, nodePort int32) {
+	svc := &api_v1.Service{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       api_v1.ServiceSpec{Ports: []api_v1.ServicePort{{NodePort: nodePort}}},
+	}
+	f.ctx.ServiceInformer.GetIndexer().Add(svc)
+}
+
+// Apply adds/updates ing in the fixture's Ingress store and synchronously
+// syncs it, as LoadBalancerController's worker would on the next item off
+// its queue.
+func (f *ControllerFixture) Apply(ing *extensions.Ingress) error {
+	// sync writes the Ingress's status back through the clientset, so the
+	// Ingress has to exist there as well as in the informer store.
+	if _, err := f.ctx.KubeClient.Extensions().Ingresses(ing.Namespace).Create(ing); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	f.ctx.IngressInformer.GetIndexer().Add(ing)
+
+	key, err := cache.MetaNamespaceKeyFunc(ing)
+	if err != nil {
+		return err
+	}
+	f.namespace = ing.Namespace
+	f.key = key
+	return f.lbc.Sync(key)
+}
+
+// Delete removes ing from the fixture's Ingress store and synchronously
+// syncs its key, so the controller garbage collects its GCE resources.
+func (f *ControllerFixture) Delete(ing *extensions.Ingress) error {
+	if err := f.ctx.IngressInformer.GetIndexer().Delete(ing); err != nil {
+		return err
+	}
+	key, err := cache.MetaNamespaceKeyFunc(ing)
+	if err != nil {
+		return err
+	}
+	return f.lbc.Sync(key)
+}
+
+// WaitForURLMap asserts that the last-Applied Ingress's L7 has pushed a
+// UrlMap routing want's hosts/paths to the node ports RegisterService
+// assigned their backend Services. It's named WaitFor* for parity with the
+// polling verbs a real-GCE-backed fixture would need; since this fixture's
+// Apply is synchronous, there's nothing to actually poll.
+func (f *ControllerFixture) WaitForURLMap(want utils.PrimitivePathMap) error {
+	l7, err := f.cm.GetL7(f.key)
+	if err != nil {
+		return err
+	}
+
+	wantMap := utils.NewGCEURLMap()
+	wantMap.DefaultBackend = l7.RuntimeInfo().UrlMap.DefaultBackend
+	for host, paths := range want {
+		var rules []utils.PathRule
+		for path, svcName := range paths {
+			nodePort, err := f.serviceNodePort(svcName)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, utils.PathRule{Path: path, Backend: utils.ServicePort{NodePort: nodePort}})
+		}
+		wantMap.PutPathRulesForHost(host, rules)
+	}
+	return f.fakeLbs.CheckURLMap(l7, wantMap)
+}
+
+// WaitForForwardingRules asserts that the last-Applied Ingress's L7 has
+// exactly wantCount forwarding rules sharing a single IP, and returns that
+// IP.
+func (f *ControllerFixture) WaitForForwardingRules(wantCount int) (string, error) {
+	l7, err := f.cm.GetL7(f.key)
+	if err != nil {
+		return "", err
+	}
+
+	ip := l7.IP()
+	rules := f.fakeLbs.GetForwardingRulesWithIPs([]string{ip})
+	if len(rules) != wantCount {
+		return "", fmt.Errorf("len(forwarding rules with IP %q) = %d, want %d", ip, len(rules), wantCount)
+	}
+	return ip, nil
+}
+
+// SimulateReachability resolves the status code a request for path on host
+// would get back, by walking the last-Applied Ingress's L7 the way GCE's
+// load balancer would: forwarding rule -> URL map host rule -> path rule
+// (falling back to the URL map's default backend) -> backend service. It
+// returns http.StatusNotFound if the L7 has no forwarding rule yet, and
+// http.StatusServiceUnavailable if routing resolves to a backend that's
+// since been garbage collected -- both deterministic, in-memory stand-ins
+// for what an unreachable real GCE load balancer would actually do.
+func (f *ControllerFixture) SimulateReachability(host, path string) (int, error) {
+	l7, err := f.cm.GetL7(f.key)
+	if err != nil {
+		return 0, err
+	}
+	if len(f.fakeLbs.GetForwardingRulesWithIPs([]string{l7.IP()})) == 0 {
+		return http.StatusNotFound, nil
+	}
+
+	urlMap := l7.RuntimeInfo().UrlMap
+	nodePort := urlMap.DefaultBackend
+	if matcherName, ok := urlMap.HostRules()[host]; ok {
+		for _, rule := range urlMap.PathMatchers()[matcherName] {
+			if rule.Path == path {
+				nodePort = rule.Backend.NodePort
+				break
+			}
+		}
+	}
+
+	if !f.cm.BackendExists(nodePort) {
+		return http.StatusServiceUnavailable, nil
+	}
+	return http.StatusOK, nil
+}
+
+// serviceNodePort resolves svcName's node port via the fixture's Service
+// store, in the last-Applied Ingress's namespace.
+func (f *ControllerFixture) serviceNodePort(svcName string) (int64, error) {
+	obj, exists, err := f.ctx.ServiceInformer.GetIndexer().GetByKey(f.namespace + "/" + svcName)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, fmt.Errorf("no registered service %s/%s", f.namespace, svcName)
+	}
+	svc := obj.(*api_v1.Service)
+	if len(svc.Spec.Ports) == 0 {
+		return 0, fmt.Errorf("service %s/%s has no ports", f.namespace, svcName)
+	}
+	return int64(svc.Spec.Ports[0].NodePort), nil
+}
+
+// Cleanup releases the fixture's resources.
+func (f *ControllerFixture) Cleanup() {
+	close(f.stopCh)
+}
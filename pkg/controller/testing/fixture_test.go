@@ -0,0 +1,273 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"net/http"
+	"testing"
+
+	compute "google.golang.org/api/compute/v1"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-gce/pkg/annotations"
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+var testPort = intstr.FromInt(80)
+
+func testIngress(name string, paths utils.PrimitivePathMap) *extensions.Ingress {
+	rules := []extensions.IngressRule{}
+	for host, pathMap := range paths {
+		httpPaths := []extensions.HTTPIngressPath{}
+		for path, backend := range pathMap {
+			httpPaths = append(httpPaths, extensions.HTTPIngressPath{
+				Path:    path,
+				Backend: extensions.IngressBackend{ServiceName: backend, ServicePort: testPort},
+			})
+		}
+		rules = append(rules, extensions.IngressRule{
+			Host:             host,
+			IngressRuleValue: extensions.IngressRuleValue{HTTP: &extensions.HTTPIngressRuleValue{Paths: httpPaths}},
+		})
+	}
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       extensions.IngressSpec{Rules: rules},
+	}
+}
+
+func TestFixtureSharedBackendAcrossHosts(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	f.RegisterService("default", "appsvc", 30001)
+	ing := testIngress("ing1", utils.PrimitivePathMap{
+		"foo.example.com": {"/app": "appsvc"},
+		"bar.example.com": {"/app": "appsvc"},
+	})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	if err := f.WaitForURLMap(utils.PrimitivePathMap{
+		"foo.example.com": {"/app": "appsvc"},
+		"bar.example.com": {"/app": "appsvc"},
+	}); err != nil {
+		t.Errorf("WaitForURLMap() = %v, want nil", err)
+	}
+}
+
+func TestFixtureForwardingRulesShareAnIP(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	f.RegisterService("default", "appsvc", 30001)
+	ing := testIngress("ing1", utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	// No TLS cert, so only the HTTP forwarding rule exists.
+	if _, err := f.WaitForForwardingRules(1); err != nil {
+		t.Errorf("WaitForForwardingRules(1) = %v, want nil", err)
+	}
+}
+
+func TestFixtureChangingStaticIPMovesForwardingRule(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	f.RegisterService("default", "appsvc", 30001)
+	ing := testIngress("ing1", utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	if _, err := f.WaitForForwardingRules(1); err != nil {
+		t.Fatalf("WaitForForwardingRules(1) = %v, want nil", err)
+	}
+
+	ing.Annotations = map[string]string{annotations.StaticIPNameKey: "testip"}
+	f.fakeLbs.ReserveGlobalAddress(&compute.Address{Name: "testip", Address: "1.2.3.4"})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	ip, err := f.WaitForForwardingRules(1)
+	if err != nil {
+		t.Fatalf("WaitForForwardingRules(1) = %v, want nil", err)
+	}
+	if ip != "1.2.3.4" {
+		t.Errorf("forwarding rule IP = %q, want 1.2.3.4", ip)
+	}
+}
+
+func TestFixtureSimulateReachability(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	f.RegisterService("default", "appsvc", 30001)
+	ing := testIngress("ing1", utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	if code, err := f.SimulateReachability("foo.example.com", "/app"); err != nil || code != http.StatusOK {
+		t.Errorf("SimulateReachability(foo.example.com, /app) = %d, %v, want 200, nil", code, err)
+	}
+	// A host/path the Ingress never registered a rule for falls through to
+	// the URL map's default backend, which is always ensured alongside the
+	// rest of the URL map -- so it's still reachable, just routed
+	// differently than the caller may have expected.
+	if code, err := f.SimulateReachability("other.example.com", "/nope"); err != nil || code != http.StatusOK {
+		t.Errorf("SimulateReachability(other.example.com, /nope) = %d, %v, want 200 (falls back to the default backend), nil", code, err)
+	}
+
+	if err := f.Delete(ing); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if code, err := f.SimulateReachability("foo.example.com", "/app"); err == nil {
+		t.Errorf("SimulateReachability() after Delete = %d, %v, want an error since the L7 was torn down", code, err)
+	}
+}
+
+// TestFixtureDefaultsEmptyHostAndPath mirrors pkg/controller's
+// TestLbDefaulting through the fixture's public API: an Ingress rule with
+// no host or path should route through loadbalancers.DefaultHost/DefaultPath.
+func TestFixtureDefaultsEmptyHostAndPath(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	f.RegisterService("default", "appsvc", 30001)
+	ing := testIngress("ing1", utils.PrimitivePathMap{"": {"": "appsvc"}})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	if err := f.WaitForURLMap(utils.PrimitivePathMap{
+		loadbalancers.DefaultHost: {loadbalancers.DefaultPath: "appsvc"},
+	}); err != nil {
+		t.Errorf("WaitForURLMap() = %v, want nil", err)
+	}
+}
+
+// TestFixtureServiceAppearingLaterFillsInURLMap mirrors pkg/controller's
+// TestLbNoService: an Ingress Applied before its backend Service exists
+// gets an incomplete URL map, which a later Apply -- once the Service has
+// been registered -- corrects.
+func TestFixtureServiceAppearingLaterFillsInURLMap(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	ing := testIngress("ing1", utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+	if err := f.WaitForURLMap(utils.PrimitivePathMap{"foo.example.com": {}}); err != nil {
+		t.Errorf("WaitForURLMap() before appsvc is registered = %v, want nil (an empty PathMatcher for foo.example.com)", err)
+	}
+
+	f.RegisterService("default", "appsvc", 30001)
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() after RegisterService = %v", err)
+	}
+	if err := f.WaitForURLMap(utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}}); err != nil {
+		t.Errorf("WaitForURLMap() after appsvc is registered = %v, want nil", err)
+	}
+}
+
+// TestFixtureFaultyUrlMapIsCorrectedOnResync mirrors pkg/controller's
+// TestLbFaultyUpdate: state that drifts out from under the controller (an
+// L7's UrlMap mutated directly, simulating an out-of-band GCE change) is
+// overwritten back to the Ingress's desired state on the next Apply.
+func TestFixtureFaultyUrlMapIsCorrectedOnResync(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	f.RegisterService("default", "appsvc", 30001)
+	f.RegisterService("default", "othersvc", 30002)
+	ing := testIngress("ing1", utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	l7, err := f.cm.GetL7(f.key)
+	if err != nil {
+		t.Fatalf("cm.GetL7() = %v", err)
+	}
+	l7.RuntimeInfo().UrlMap = utils.NewGCEURLMap()
+	l7.RuntimeInfo().UrlMap.DefaultBackend = 30002
+	l7.RuntimeInfo().UrlMap.PutPathRulesForHost("foo.example.com", []utils.PathRule{
+		{Path: "/app", Backend: utils.ServicePort{NodePort: 30002}},
+	})
+	if err := l7.UpdateUrlMap(); err != nil {
+		t.Fatalf("l7.UpdateUrlMap() = %v", err)
+	}
+
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() (resync) = %v", err)
+	}
+	if err := f.WaitForURLMap(utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}}); err != nil {
+		t.Errorf("WaitForURLMap() after resync = %v, want nil (drift corrected)", err)
+	}
+}
+
+func TestFixtureDeleteGarbageCollectsResources(t *testing.T) {
+	f, err := NewControllerFixture()
+	if err != nil {
+		t.Fatalf("NewControllerFixture() = %v", err)
+	}
+	defer f.Cleanup()
+
+	f.RegisterService("default", "appsvc", 30001)
+	ing := testIngress("ing1", utils.PrimitivePathMap{"foo.example.com": {"/app": "appsvc"}})
+	if err := f.Apply(ing); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+	if err := f.Delete(ing); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+
+	if err := f.WaitForURLMap(nil); err == nil {
+		t.Errorf("WaitForURLMap() = nil, want an error since the Ingress's L7 was torn down")
+	}
+}
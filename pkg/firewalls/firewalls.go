@@ -0,0 +1,95 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package firewalls manages the single firewall rule that opens every
+// Ingress-referenced node port to GCE's L7 load balancers.
+package firewalls
+
+import (
+	"fmt"
+	"sync"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+// Namer names the firewall rule a cluster's Ingress controller creates.
+type Namer interface {
+	FirewallRule() string
+}
+
+// SingleFirewallPool manages the one firewall rule opening node ports to
+// GCE's L7 load balancers for every node in the cluster. It's "single"
+// because every Ingress in the cluster shares the same rule, kept in sync
+// with the union of all their backends' node ports.
+type SingleFirewallPool interface {
+	// EnsureFirewall creates or updates the cluster's firewall rule to open
+	// exactly nodePorts.
+	EnsureFirewall(nodePorts []int64) error
+	// Shutdown removes the cluster's firewall rule. Callers should only do
+	// this once no Ingress remains.
+	Shutdown() error
+}
+
+// FirewallRules is the in-memory SingleFirewallPool backing this tree's fake
+// cluster manager; there's no vendored GCE client here to wrap.
+type FirewallRules struct {
+	mu    sync.Mutex
+	namer Namer
+
+	rule *compute.Firewall
+}
+
+// NewFirewallPool returns a FirewallRules with no rule yet created.
+func NewFirewallPool(namer Namer) *FirewallRules {
+	return &FirewallRules{namer: namer}
+}
+
+// EnsureFirewall implements SingleFirewallPool.
+func (f *FirewallRules) EnsureFirewall(nodePorts []int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ports := make([]string, 0, len(nodePorts))
+	for _, p := range nodePorts {
+		ports = append(ports, fmt.Sprintf("%d", p))
+	}
+	f.rule = &compute.Firewall{
+		Name:    f.namer.FirewallRule(),
+		Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: ports}},
+	}
+	return nil
+}
+
+// Shutdown implements SingleFirewallPool.
+func (f *FirewallRules) Shutdown() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rule = nil
+	return nil
+}
+
+// GetFirewall returns the cluster's firewall rule if it exists and is named
+// name. There's no other consumer of this: the controller only ever needs
+// to ensure the rule exists, never read it back, so this exists for tests.
+func (f *FirewallRules) GetFirewall(name string) (*compute.Firewall, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.rule == nil || f.rule.Name != name {
+		return nil, fmt.Errorf("no firewall rule named %q", name)
+	}
+	return f.rule, nil
+}
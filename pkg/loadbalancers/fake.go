@@ -0,0 +1,131 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	compute "google.golang.org/api/compute/v1"
+
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// FakeLoadBalancers is an in-memory stand-in for the GCE resources an L7
+// creates. This tree has no vendored GCE client (real or cloud-provider
+// fake) to wrap, so FakeLoadBalancers is the only backing L7Pool has; it
+// plays the role pkg/controller's tests otherwise hand-roll per assertion.
+type FakeLoadBalancers struct {
+	Um        map[string]*compute.UrlMap
+	Tp        map[string]*compute.TargetHttpProxy
+	Tps       map[string]*compute.TargetHttpsProxy
+	Fw        map[string]*compute.ForwardingRule
+	Addresses map[string]*compute.Address
+
+	// ServerTLSPolicies and ProxyServerTLSPolicy back ensureClientTLSPolicy's
+	// mTLS-termination wiring; see tls_passthrough.go.
+	ServerTLSPolicies    map[string]*ServerTLSPolicy
+	ProxyServerTLSPolicy map[string]string
+}
+
+// NewFakeLoadBalancers returns an empty FakeLoadBalancers.
+func NewFakeLoadBalancers() *FakeLoadBalancers {
+	return &FakeLoadBalancers{
+		Um:                   map[string]*compute.UrlMap{},
+		Tp:                   map[string]*compute.TargetHttpProxy{},
+		Tps:                  map[string]*compute.TargetHttpsProxy{},
+		Fw:                   map[string]*compute.ForwardingRule{},
+		Addresses:            map[string]*compute.Address{},
+		ServerTLSPolicies:    map[string]*ServerTLSPolicy{},
+		ProxyServerTLSPolicy: map[string]string{},
+	}
+}
+
+// ReserveGlobalAddress records addr as already reserved, as if an earlier
+// compute.GlobalAddresses.Insert call (or a user pre-reserving one) had
+// succeeded.
+func (f *FakeLoadBalancers) ReserveGlobalAddress(addr *compute.Address) {
+	f.Addresses[addr.Name] = addr
+}
+
+// reserveEphemeralAddress mints and records a deterministic (but otherwise
+// arbitrary) IP for an L7 that wasn't handed a StaticIPName, the way GCE
+// would allocate one automatically for an ephemeral forwarding rule.
+func (f *FakeLoadBalancers) reserveEphemeralAddress(seed string) string {
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	n := h.Sum32()
+	ip := fmt.Sprintf("10.%d.%d.%d", (n>>16)&0xff, (n>>8)&0xff, n&0xff)
+	f.Addresses["ephemeral-"+seed] = &compute.Address{Name: "ephemeral-" + seed, Address: ip}
+	return ip
+}
+
+// GetForwardingRulesWithIPs returns every ForwardingRule whose IPAddress is
+// in ips.
+func (f *FakeLoadBalancers) GetForwardingRulesWithIPs(ips []string) []*compute.ForwardingRule {
+	want := map[string]bool{}
+	for _, ip := range ips {
+		want[ip] = true
+	}
+	var out []*compute.ForwardingRule
+	for _, fr := range f.Fw {
+		if want[fr.IPAddress] {
+			out = append(out, fr)
+		}
+	}
+	return out
+}
+
+// CheckURLMap asserts that l7's pushed routing state matches want's
+// host/path/backend shape. It compares the utils.GCEURLMap l7 last built
+// directly, rather than re-deriving a compute.UrlMap, since that's exactly
+// the representation L7RuntimeInfo.UrlMap is mutated through in tests.
+func (f *FakeLoadBalancers) CheckURLMap(l7 *L7, want *utils.GCEURLMap) error {
+	if l7.runtimeInfo == nil || l7.runtimeInfo.UrlMap == nil {
+		return fmt.Errorf("L7 %q has no UrlMap", l7.name())
+	}
+	got := l7.runtimeInfo.UrlMap
+
+	if got.DefaultBackend != want.DefaultBackend {
+		return fmt.Errorf("DefaultBackend = %d, want %d", got.DefaultBackend, want.DefaultBackend)
+	}
+	if len(got.HostRules()) != len(want.HostRules()) {
+		return fmt.Errorf("HostRules = %v, want %v", got.HostRules(), want.HostRules())
+	}
+	for host, wantMatcher := range want.HostRules() {
+		gotMatcher, ok := got.HostRules()[host]
+		if !ok {
+			return fmt.Errorf("missing HostRule for %q", host)
+		}
+		if !utils.SamePathRuleSet(got.PathMatchers()[gotMatcher], want.PathMatchers()[wantMatcher]) {
+			return fmt.Errorf("PathMatcher for host %q = %v, want %v", host, got.PathMatchers()[gotMatcher], want.PathMatchers()[wantMatcher])
+		}
+	}
+	return nil
+}
+
+func httpProxy(name, urlMapName string) *compute.TargetHttpProxy {
+	return &compute.TargetHttpProxy{Name: name, UrlMap: urlMapName}
+}
+
+func httpsProxy(name, urlMapName string) *compute.TargetHttpsProxy {
+	return &compute.TargetHttpsProxy{Name: name, UrlMap: urlMapName}
+}
+
+func forwardingRule(name, ip, target, portRange string) *compute.ForwardingRule {
+	return &compute.ForwardingRule{Name: name, IPAddress: ip, Target: target, PortRange: portRange}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	"fmt"
+
+	"k8s.io/ingress-gce/pkg/annotations"
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// Namer names the GCE resources an L7 creates. *utils.Namer satisfies this.
+type Namer interface {
+	UrlMap(lbName string) string
+	TargetProxy(lbName, protocol string) string
+	ForwardingRule(lbName, protocol string) string
+}
+
+// L7RuntimeInfo is the Ingress-derived state LoadBalancerController hands to
+// L7Pool.Ensure on every sync. L7 keeps a reference to the struct itself
+// (not a copy), so a caller holding the return value of L7.RuntimeInfo can
+// mutate it and re-push via L7.UpdateUrlMap -- tests use this to simulate
+// state drifting out from under the controller.
+type L7RuntimeInfo struct {
+	// Name is the Ingress's store key (namespace/name) and doubles as the
+	// GCE resource name root Namer derives every other name from.
+	Name string
+	// UrlMap is the desired routing state, already expanded with
+	// DefaultHost/DefaultPath and deduped by utils.GCEURLMap.
+	UrlMap *utils.GCEURLMap
+	// TLS holds the serving certificate/key pairs this L7's HTTPS target
+	// proxy should use. No HTTPS forwarding rule is created when empty.
+	TLS []*TLSCerts
+	// StaticIPName is the name of a reserved global Address to use
+	// instead of an ephemeral IP, or "" to use an ephemeral IP.
+	StaticIPName string
+	// ExistingIP is the IP this Ingress's status already reports, if any.
+	// resolveIP reuses it in place of minting a new ephemeral IP, so that a
+	// restarted controller doesn't churn every Ingress's IP on its first
+	// sync after coming back up. Ignored when StaticIPName is set.
+	ExistingIP string
+	// Ingress carries the Ingress's annotations, read by the redirect and
+	// client-cert pass-through logic UpdateUrlMap/edgeHop apply.
+	Ingress annotations.IngressAnnotations
+	// TrustedCACert is the PEM-encoded CA bundle (loaded via
+	// tls.TLSSecretLoader.GetCACertFromSecret) client certificates are
+	// validated against when Ingress.PassTLSCert() is true. Empty if the
+	// Ingress didn't reference a client-cert-trust Secret.
+	TrustedCACert string
+}
+
+// L7 represents a single GCE HTTP(S) load balancer backing one Ingress.
+type L7 struct {
+	cloud          *FakeLoadBalancers
+	namer          Namer
+	linkForBackend func(nodePort int64) string
+
+	runtimeInfo *L7RuntimeInfo
+	ip          string
+	// httpUrlMapName is the name of the UrlMap the HTTP target proxy
+	// currently points at -- the regular one, or a redirect-only one when
+	// ensureRedirectUrlMap decides the Ingress wants force-ssl-redirect.
+	httpUrlMapName string
+}
+
+// RuntimeInfo returns the L7RuntimeInfo Ensure last pushed to l. Tests use
+// the returned pointer to mutate state out from under the controller and
+// then call UpdateUrlMap directly, to verify a later sync corrects it.
+func (l *L7) RuntimeInfo() *L7RuntimeInfo {
+	return l.runtimeInfo
+}
+
+// IP returns the IP address the last edgeHop resolved for this L7, or ""
+// if edgeHop hasn't run yet.
+func (l *L7) IP() string {
+	return l.ip
+}
+
+// UpdateUrlMap pushes l.runtimeInfo.UrlMap to the cloud as a compute UrlMap,
+// resolving which UrlMap (the regular one, or a redirect-only one) the HTTP
+// target proxy should reference along the way.
+func (l *L7) UpdateUrlMap() error {
+	if l.runtimeInfo == nil || l.runtimeInfo.UrlMap == nil {
+		return fmt.Errorf("L7 %q has no UrlMap to push", l.name())
+	}
+
+	name := l.namer.UrlMap(l.runtimeInfo.Name)
+	um := urlMapFromGCEURLMap(name, l.runtimeInfo.UrlMap, l.linkForBackend)
+	l.cloud.Um[name] = um
+
+	hosts := make([]string, 0, len(l.runtimeInfo.UrlMap.HostRules()))
+	for h := range l.runtimeInfo.UrlMap.HostRules() {
+		hosts = append(hosts, h)
+	}
+	l.httpUrlMapName = l.ensureRedirectUrlMap(name, hosts)
+
+	return nil
+}
+
+func (l *L7) name() string {
+	if l.runtimeInfo == nil {
+		return ""
+	}
+	return l.runtimeInfo.Name
+}
+
+// resolveIP returns the IP address this L7's forwarding rules should use:
+// the address reserved under StaticIPName if one is configured, the IP a
+// previous edgeHop already settled on, the Ingress's ExistingIP if this is
+// the first edgeHop since the controller (re)started, or a freshly minted
+// ephemeral one.
+func (l *L7) resolveIP() (string, error) {
+	if name := l.runtimeInfo.StaticIPName; name != "" {
+		addr, ok := l.cloud.Addresses[name]
+		if !ok {
+			return "", fmt.Errorf("reserved address %q not found", name)
+		}
+		return addr.Address, nil
+	}
+	if l.ip != "" {
+		return l.ip, nil
+	}
+	if l.runtimeInfo.ExistingIP != "" {
+		return l.runtimeInfo.ExistingIP, nil
+	}
+	return l.cloud.reserveEphemeralAddress(l.namer.UrlMap(l.runtimeInfo.Name)), nil
+}
+
+// edgeHop reconciles every GCE resource this L7 owns (UrlMap(s), target
+// proxies, forwarding rules) with l.runtimeInfo. It's called by L7Pool on
+// every Ensure, and runs unconditionally: there's no real, billed API call
+// underneath it to spare here, just in-memory fakes.
+func (l *L7) edgeHop() error {
+	ip, err := l.resolveIP()
+	if err != nil {
+		return err
+	}
+	l.ip = ip
+
+	if err := l.UpdateUrlMap(); err != nil {
+		return err
+	}
+
+	httpProxyName := l.namer.TargetProxy(l.runtimeInfo.Name, "http")
+	l.cloud.Tp[httpProxyName] = httpProxy(httpProxyName, l.httpUrlMapName)
+
+	httpFwName := l.namer.ForwardingRule(l.runtimeInfo.Name, "http")
+	l.cloud.Fw[httpFwName] = forwardingRule(httpFwName, l.ip, httpProxyName, "80")
+
+	httpsProxyName := l.namer.TargetProxy(l.runtimeInfo.Name, "https")
+	httpsFwName := l.namer.ForwardingRule(l.runtimeInfo.Name, "https")
+
+	if len(l.runtimeInfo.TLS) == 0 {
+		delete(l.cloud.Tps, httpsProxyName)
+		delete(l.cloud.Fw, httpsFwName)
+		l.ensureClientTLSPolicy(httpsProxyName, TLSPassThroughConfig{})
+		return nil
+	}
+
+	l.cloud.Tps[httpsProxyName] = httpsProxy(httpsProxyName, l.namer.UrlMap(l.runtimeInfo.Name))
+	l.cloud.Fw[httpsFwName] = forwardingRule(httpsFwName, l.ip, httpsProxyName, "443")
+
+	l.ensureClientTLSPolicy(httpsProxyName, TLSPassThroughConfig{
+		Enabled:       l.runtimeInfo.Ingress.PassTLSCert(),
+		HeaderName:    l.runtimeInfo.Ingress.PassTLSHeaderName(),
+		TrustedCACert: l.runtimeInfo.TrustedCACert,
+	})
+	return nil
+}
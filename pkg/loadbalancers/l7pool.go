@@ -0,0 +1,136 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	"fmt"
+	"sync"
+)
+
+// LoadBalancerPool manages the L7s backing a cluster's Ingresses.
+type LoadBalancerPool interface {
+	// Get returns the L7 for key (an Ingress store key), or an error if
+	// Ensure hasn't been called for it yet.
+	Get(key string) (*L7, error)
+	// Ensure creates or updates the L7 for info.Name, pushing info's
+	// routing/TLS/cert state to the cloud, and returns it.
+	Ensure(info *L7RuntimeInfo) (*L7, error)
+	// Delete tears down the L7 for key, if one exists.
+	Delete(key string) error
+	// GC removes every L7 not named in keepKeys.
+	GC(keepKeys []string) error
+}
+
+// L7Pool is the LoadBalancerPool backed by a FakeLoadBalancers.
+type L7Pool struct {
+	mu             sync.Mutex
+	cloud          *FakeLoadBalancers
+	namer          Namer
+	linkForBackend func(nodePort int64) string
+
+	l7s map[string]*L7
+}
+
+// NewL7Pool returns an L7Pool backed by cloud.
+func NewL7Pool(cloud *FakeLoadBalancers, namer Namer, linkForBackend func(nodePort int64) string) *L7Pool {
+	return &L7Pool{
+		cloud:          cloud,
+		namer:          namer,
+		linkForBackend: linkForBackend,
+		l7s:            map[string]*L7{},
+	}
+}
+
+// Get returns the L7 for key, or an error if Ensure hasn't been called for
+// it yet.
+func (p *L7Pool) Get(key string) (*L7, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	l7, ok := p.l7s[key]
+	if !ok {
+		return nil, fmt.Errorf("no L7 found for %q", key)
+	}
+	return l7, nil
+}
+
+// Ensure creates the L7 for info.Name if it doesn't exist yet, then pushes
+// info's state to the cloud via edgeHop.
+func (p *L7Pool) Ensure(info *L7RuntimeInfo) (*L7, error) {
+	// The lock is held through edgeHop: every L7 writes into the same
+	// shared cloud maps.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	l7, ok := p.l7s[info.Name]
+	if !ok {
+		l7 = &L7{cloud: p.cloud, namer: p.namer, linkForBackend: p.linkForBackend}
+		p.l7s[info.Name] = l7
+	}
+
+	l7.runtimeInfo = info
+	if err := l7.edgeHop(); err != nil {
+		return nil, err
+	}
+	return l7, nil
+}
+
+// Delete tears down the L7 for key, if one exists.
+func (p *L7Pool) Delete(key string) error {
+	p.mu.Lock()
+	l7, ok := p.l7s[key]
+	if !ok {
+		p.mu.Unlock()
+		return nil
+	}
+	delete(p.l7s, key)
+	p.mu.Unlock()
+
+	delete(p.cloud.Um, p.namer.UrlMap(key))
+	delete(p.cloud.Um, p.namer.UrlMap(key)+"-redirect")
+	httpsProxyName := p.namer.TargetProxy(key, "https")
+	delete(p.cloud.Tp, p.namer.TargetProxy(key, "http"))
+	delete(p.cloud.Tps, httpsProxyName)
+	delete(p.cloud.Fw, p.namer.ForwardingRule(key, "http"))
+	delete(p.cloud.Fw, p.namer.ForwardingRule(key, "https"))
+	l7.ensureClientTLSPolicy(httpsProxyName, TLSPassThroughConfig{})
+	return nil
+}
+
+// GC removes every L7 not named in keepKeys.
+func (p *L7Pool) GC(keepKeys []string) error {
+	keep := map[string]bool{}
+	for _, k := range keepKeys {
+		keep[k] = true
+	}
+
+	p.mu.Lock()
+	var toDelete []string
+	for key := range p.l7s {
+		if !keep[key] {
+			toDelete = append(toDelete, key)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, key := range toDelete {
+		if err := p.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
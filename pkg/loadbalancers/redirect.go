@@ -0,0 +1,88 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	compute "google.golang.org/api/compute/v1"
+
+	"k8s.io/ingress-gce/pkg/annotations"
+)
+
+// redirectResponseCodes maps the HTTP status code accepted by the
+// force-ssl-redirect annotation to the enum value GCE's HttpRedirectAction
+// expects.
+var redirectResponseCodes = map[string]string{
+	"301": "MOVED_PERMANENTLY_DEFAULT",
+	"302": "FOUND",
+	"307": "TEMPORARY_REDIRECT",
+	"308": "PERMANENT_REDIRECT",
+}
+
+// shouldRedirectToHTTPS reports whether an Ingress carrying ing's
+// annotations should have its HTTP target proxy redirect to HTTPS. This
+// requires both the annotation and a usable HTTPS frontend: the redirect is
+// pointless, and actively breaks the Ingress, if there's no HTTPS listener
+// for it to send traffic to.
+func shouldRedirectToHTTPS(ing annotations.IngressAnnotations, hasTLSCert, hasStaticIP bool) bool {
+	return ing.ForceSSLRedirect() && (hasTLSCert || hasStaticIP)
+}
+
+// redirectUrlMapFromGCEURLMap builds the UrlMap that L7.ensureRedirectUrlMap
+// attaches to an Ingress's HTTP target proxy in place of the regular UrlMap
+// when the Ingress carries annotations.SSLRedirectKey: every host in hosts
+// redirects to the https scheme of the same host and path instead of being
+// forwarded to its backend. The HTTPS target proxy is left pointing at the
+// regular UrlMap produced by urlMapFromGCEURLMap, so backends themselves are
+// unaffected.
+func redirectUrlMapFromGCEURLMap(name string, hosts []string, ing annotations.IngressAnnotations) *compute.UrlMap {
+	redirectAction := &compute.HttpRedirectAction{
+		HttpsRedirect:        true,
+		RedirectResponseCode: redirectResponseCodes[ing.SSLRedirectResponseCode()],
+	}
+
+	um := &compute.UrlMap{
+		Name:               name,
+		DefaultUrlRedirect: redirectAction,
+		PathMatchers: []*compute.PathMatcher{{
+			Name:               name,
+			DefaultUrlRedirect: redirectAction,
+		}},
+	}
+	for _, h := range hosts {
+		um.HostRules = append(um.HostRules, &compute.HostRule{
+			Hosts:       []string{h},
+			PathMatcher: name,
+		})
+	}
+	return um
+}
+
+// ensureRedirectUrlMap decides whether l's HTTP target proxy should point at
+// a redirect-only UrlMap instead of regularName, and keeps l.cloud.Um in
+// sync with that decision. It returns the name the HTTP target proxy should
+// use.
+func (l *L7) ensureRedirectUrlMap(regularName string, hosts []string) string {
+	redirectName := regularName + "-redirect"
+
+	if !shouldRedirectToHTTPS(l.runtimeInfo.Ingress, len(l.runtimeInfo.TLS) > 0, l.runtimeInfo.StaticIPName != "") {
+		delete(l.cloud.Um, redirectName)
+		return regularName
+	}
+
+	l.cloud.Um[redirectName] = redirectUrlMapFromGCEURLMap(redirectName, hosts, l.runtimeInfo.Ingress)
+	return redirectName
+}
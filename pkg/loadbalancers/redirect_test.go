@@ -0,0 +1,73 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	"testing"
+
+	"k8s.io/ingress-gce/pkg/annotations"
+)
+
+func TestShouldRedirectToHTTPS(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		ing         annotations.IngressAnnotations
+		hasTLSCert  bool
+		hasStaticIP bool
+		want        bool
+	}{
+		{"no annotation", annotations.IngressAnnotations{}, true, true, false},
+		{"annotation without https frontend", annotations.IngressAnnotations{annotations.SSLRedirectKey: "true"}, false, false, false},
+		{"annotation with tls cert", annotations.IngressAnnotations{annotations.SSLRedirectKey: "true"}, true, false, true},
+		{"annotation with static ip", annotations.IngressAnnotations{annotations.SSLRedirectKey: "true"}, false, true, true},
+		{"annotation false", annotations.IngressAnnotations{annotations.SSLRedirectKey: "false"}, true, true, false},
+	} {
+		if got := shouldRedirectToHTTPS(tc.ing, tc.hasTLSCert, tc.hasStaticIP); got != tc.want {
+			t.Errorf("%s: shouldRedirectToHTTPS() = %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestRedirectUrlMapFromGCEURLMap(t *testing.T) {
+	ing := annotations.IngressAnnotations{annotations.SSLRedirectKey: "true"}
+	hosts := []string{"foo.example.com", "bar.example.com"}
+
+	um := redirectUrlMapFromGCEURLMap("k8s-um-redirect", hosts, ing)
+
+	if got, want := len(um.HostRules), len(hosts); got != want {
+		t.Fatalf("len(um.HostRules) = %d, want %d", got, want)
+	}
+	if um.DefaultUrlRedirect == nil || !um.DefaultUrlRedirect.HttpsRedirect {
+		t.Fatalf("um.DefaultUrlRedirect = %+v, want HttpsRedirect = true", um.DefaultUrlRedirect)
+	}
+	if got, want := um.DefaultUrlRedirect.RedirectResponseCode, "MOVED_PERMANENTLY_DEFAULT"; got != want {
+		t.Errorf("um.DefaultUrlRedirect.RedirectResponseCode = %q, want %q", got, want)
+	}
+}
+
+func TestRedirectUrlMapFromGCEURLMapCustomResponseCode(t *testing.T) {
+	ing := annotations.IngressAnnotations{
+		annotations.SSLRedirectKey:             "true",
+		annotations.SSLRedirectResponseCodeKey: "307",
+	}
+
+	um := redirectUrlMapFromGCEURLMap("k8s-um-redirect", []string{"foo.example.com"}, ing)
+
+	if got, want := um.DefaultUrlRedirect.RedirectResponseCode, "TEMPORARY_REDIRECT"; got != want {
+		t.Errorf("um.DefaultUrlRedirect.RedirectResponseCode = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+const (
+	// DefaultHost is the host used for an Ingress rule that leaves the
+	// host field empty.
+	DefaultHost = "*"
+	// DefaultPath is the path used for an Ingress rule that leaves the
+	// path field empty.
+	DefaultPath = "/*"
+)
+
+// TLSCerts holds the PEM-encoded serving certificate/key pair for a
+// target proxy's SslCertificate.
+type TLSCerts struct {
+	Key  string
+	Cert string
+}
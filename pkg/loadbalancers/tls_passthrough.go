@@ -0,0 +1,69 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+// TLSPassThroughConfig carries an Ingress's pass-tls-cert configuration into
+// L7.ensureClientTLSPolicy, which decides whether the HTTPS target proxy
+// should have a ServerTLSPolicy requiring and forwarding the client's
+// certificate.
+type TLSPassThroughConfig struct {
+	// Enabled is true if the Ingress carries annotations.PassTLSCertKey.
+	Enabled bool
+	// HeaderName is the header GCE forwards the validated client
+	// certificate under once the ServerTLSPolicy is attached.
+	HeaderName string
+	// TrustedCACert is the PEM-encoded CA bundle used to validate client
+	// certificates, loaded via tls.TLSSecretLoader.GetCACertFromSecret. It
+	// is empty if the Ingress didn't reference a client-cert-trust Secret,
+	// in which case no policy can be created even if Enabled is true.
+	TrustedCACert string
+}
+
+// ServerTLSPolicy is GCE's mechanism for terminating mutual TLS at a target
+// HTTPS proxy: once attached, GCE itself validates the client's certificate
+// against ClientValidationCA and forwards it to the backend under
+// ClientCertHeaderName. There is no per-request template GCE accepts to
+// fabricate this header from a regular HttpHeaderOption -- HeaderValue is
+// static text -- so pass-through has to be modeled as proxy configuration,
+// not as a UrlMap rewrite.
+type ServerTLSPolicy struct {
+	Name                 string
+	ClientValidationCA   string
+	ClientCertHeaderName string
+}
+
+// ensureClientTLSPolicy keeps l.cloud's ServerTLSPolicy for httpsProxyName in
+// sync with cfg: creating or updating it when client-cert pass-through is
+// enabled and a trusted CA is available, and removing it (and the proxy's
+// reference to it) otherwise.
+func (l *L7) ensureClientTLSPolicy(httpsProxyName string, cfg TLSPassThroughConfig) {
+	if !cfg.Enabled || cfg.TrustedCACert == "" {
+		if policyName, ok := l.cloud.ProxyServerTLSPolicy[httpsProxyName]; ok {
+			delete(l.cloud.ServerTLSPolicies, policyName)
+			delete(l.cloud.ProxyServerTLSPolicy, httpsProxyName)
+		}
+		return
+	}
+
+	policyName := l.namer.UrlMap(l.runtimeInfo.Name) + "-client-tls"
+	l.cloud.ServerTLSPolicies[policyName] = &ServerTLSPolicy{
+		Name:                 policyName,
+		ClientValidationCA:   cfg.TrustedCACert,
+		ClientCertHeaderName: cfg.HeaderName,
+	}
+	l.cloud.ProxyServerTLSPolicy[httpsProxyName] = policyName
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testNamer is a minimal Namer stand-in so these tests don't need the full
+// utils.Namer wiring used by the real controller.
+type testNamer struct{}
+
+func (testNamer) UrlMap(lbName string) string { return fmt.Sprintf("k8s-um-%s", lbName) }
+func (testNamer) TargetProxy(lbName, protocol string) string {
+	return fmt.Sprintf("k8s-tp-%s-%s", protocol, lbName)
+}
+func (testNamer) ForwardingRule(lbName, protocol string) string {
+	return fmt.Sprintf("k8s-fw-%s-%s", protocol, lbName)
+}
+
+func newTestL7(name string) *L7 {
+	return &L7{
+		cloud:          NewFakeLoadBalancers(),
+		namer:          testNamer{},
+		linkForBackend: fakeBackendLink,
+		runtimeInfo:    &L7RuntimeInfo{Name: name},
+	}
+}
+
+func TestEnsureClientTLSPolicyDisabled(t *testing.T) {
+	l := newTestL7("ns/ing")
+
+	l.ensureClientTLSPolicy("k8s-tps-ns-ing", TLSPassThroughConfig{Enabled: false})
+
+	if got := len(l.cloud.ServerTLSPolicies); got != 0 {
+		t.Fatalf("len(ServerTLSPolicies) = %d, want 0", got)
+	}
+	if got := len(l.cloud.ProxyServerTLSPolicy); got != 0 {
+		t.Fatalf("len(ProxyServerTLSPolicy) = %d, want 0", got)
+	}
+}
+
+func TestEnsureClientTLSPolicyEnabledWithoutTrustedCA(t *testing.T) {
+	l := newTestL7("ns/ing")
+
+	// pass-tls-cert without a resolvable CA Secret can't mint a policy: GCE
+	// has nothing to validate the client certificate against.
+	l.ensureClientTLSPolicy("k8s-tps-ns-ing", TLSPassThroughConfig{Enabled: true, HeaderName: "X-Forwarded-Client-Cert"})
+
+	if got := len(l.cloud.ServerTLSPolicies); got != 0 {
+		t.Fatalf("len(ServerTLSPolicies) = %d, want 0", got)
+	}
+}
+
+func TestEnsureClientTLSPolicyEnabled(t *testing.T) {
+	l := newTestL7("ns/ing")
+	httpsProxyName := "k8s-tps-ns-ing"
+
+	l.ensureClientTLSPolicy(httpsProxyName, TLSPassThroughConfig{
+		Enabled:       true,
+		HeaderName:    "X-Forwarded-Client-Cert",
+		TrustedCACert: "-----BEGIN CERTIFICATE-----\nfake\n-----END CERTIFICATE-----",
+	})
+
+	policyName, ok := l.cloud.ProxyServerTLSPolicy[httpsProxyName]
+	if !ok {
+		t.Fatalf("no ServerTLSPolicy attached to proxy %q", httpsProxyName)
+	}
+	policy, ok := l.cloud.ServerTLSPolicies[policyName]
+	if !ok {
+		t.Fatalf("ProxyServerTLSPolicy points at missing policy %q", policyName)
+	}
+	if policy.ClientCertHeaderName != "X-Forwarded-Client-Cert" {
+		t.Errorf("policy.ClientCertHeaderName = %q, want %q", policy.ClientCertHeaderName, "X-Forwarded-Client-Cert")
+	}
+	if policy.ClientValidationCA == "" {
+		t.Errorf("policy.ClientValidationCA is empty")
+	}
+}
+
+func TestEnsureClientTLSPolicyRemovedOnDisable(t *testing.T) {
+	l := newTestL7("ns/ing")
+	httpsProxyName := "k8s-tps-ns-ing"
+
+	l.ensureClientTLSPolicy(httpsProxyName, TLSPassThroughConfig{
+		Enabled:       true,
+		HeaderName:    "X-Forwarded-Client-Cert",
+		TrustedCACert: "fake-ca",
+	})
+	l.ensureClientTLSPolicy(httpsProxyName, TLSPassThroughConfig{Enabled: false})
+
+	if _, ok := l.cloud.ProxyServerTLSPolicy[httpsProxyName]; ok {
+		t.Fatalf("ProxyServerTLSPolicy still references a policy after pass-tls-cert was disabled")
+	}
+	if got := len(l.cloud.ServerTLSPolicies); got != 0 {
+		t.Fatalf("len(ServerTLSPolicies) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	compute "google.golang.org/api/compute/v1"
+
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+// urlMapFromGCEURLMap translates m into the compute UrlMap representation
+// GCE expects. Hosts that PutPathRulesForHost deduped onto a shared
+// PathMatcher are emitted as a single PathMatcher referenced by a HostRule
+// listing every one of those hosts, rather than one PathMatcher per host.
+// L7.UpdateUrlMap calls this to build the UrlMap it stores in its
+// FakeLoadBalancers' Um map. linkForBackend resolves a Service's node port to the
+// resource link of the backend service the caller has already ensured
+// exists.
+func urlMapFromGCEURLMap(name string, m *utils.GCEURLMap, linkForBackend func(nodePort int64) string) *compute.UrlMap {
+	um := &compute.UrlMap{
+		Name:           name,
+		DefaultService: linkForBackend(m.DefaultBackend),
+	}
+
+	// Group hosts by the PathMatcher they're attached to so each matcher
+	// is only emitted once, with every one of its hosts on its HostRule.
+	hostsByMatcher := map[string][]string{}
+	for host, matcher := range m.HostRules() {
+		hostsByMatcher[matcher] = append(hostsByMatcher[matcher], host)
+	}
+
+	for matcherName, rules := range m.PathMatchers() {
+		hosts := hostsByMatcher[matcherName]
+		if len(hosts) == 0 {
+			// No host references this matcher any more (e.g. its last
+			// host was removed from the Ingress); drop it rather than
+			// pushing an orphaned PathMatcher to GCE.
+			continue
+		}
+
+		pm := &compute.PathMatcher{
+			Name:           matcherName,
+			DefaultService: um.DefaultService,
+		}
+		for _, r := range rules {
+			pm.PathRules = append(pm.PathRules, &compute.PathRule{
+				Paths:   []string{r.Path},
+				Service: linkForBackend(r.Backend.NodePort),
+			})
+		}
+		um.PathMatchers = append(um.PathMatchers, pm)
+		um.HostRules = append(um.HostRules, &compute.HostRule{
+			Hosts:       hosts,
+			PathMatcher: matcherName,
+		})
+	}
+
+	return um
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancers
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/ingress-gce/pkg/utils"
+)
+
+func fakeBackendLink(nodePort int64) string {
+	return fmt.Sprintf("backend-services/be-%d", nodePort)
+}
+
+func TestUrlMapFromGCEURLMapSharesPathMatchers(t *testing.T) {
+	m := utils.NewGCEURLMap()
+	m.DefaultBackend = 30000
+	rules := []utils.PathRule{{Path: "/foo", Backend: utils.ServicePort{NodePort: 30001}}}
+	hosts := []string{"foo.example.com", "bar.example.com"}
+	for _, h := range hosts {
+		m.PutPathRulesForHost(h, rules)
+	}
+
+	um := urlMapFromGCEURLMap("test-um", m, fakeBackendLink)
+	if got, want := len(um.PathMatchers), 1; got != want {
+		t.Fatalf("len(um.PathMatchers) = %d, want %d", got, want)
+	}
+	if got, want := len(um.HostRules), 1; got != want {
+		t.Fatalf("len(um.HostRules) = %d, want %d", got, want)
+	}
+	if got, want := len(um.HostRules[0].Hosts), len(hosts); got != want {
+		t.Fatalf("len(um.HostRules[0].Hosts) = %d, want %d", got, want)
+	}
+}
+
+func TestUrlMapFromGCEURLMapRemovingHostKeepsSharedMatcher(t *testing.T) {
+	rules := []utils.PathRule{{Path: "/foo", Backend: utils.ServicePort{NodePort: 30001}}}
+
+	// L7.UpdateUrlMap always translates a freshly-built GCEURLMap (from
+	// the Ingress's current rules), never a mutated carry-over from the
+	// previous sync. So the real scenario to cover is: a GCEURLMap built
+	// with only the surviving host still produces the same PathMatcher,
+	// unaffected by whether some other GCEURLMap happened to share it.
+	both := utils.NewGCEURLMap()
+	both.PutPathRulesForHost("foo.example.com", rules)
+	both.PutPathRulesForHost("bar.example.com", rules)
+	before := urlMapFromGCEURLMap("test-um", both, fakeBackendLink)
+	if len(before.PathMatchers) != 1 {
+		t.Fatalf("expected a single shared PathMatcher before host removal, got %d", len(before.PathMatchers))
+	}
+
+	onlyBar := utils.NewGCEURLMap()
+	onlyBar.PutPathRulesForHost("bar.example.com", rules)
+	after := urlMapFromGCEURLMap("test-um", onlyBar, fakeBackendLink)
+	if got, want := len(after.PathMatchers), 1; got != want {
+		t.Fatalf("bar.example.com's PathMatcher was dropped when foo.example.com stopped sharing it: len(after.PathMatchers) = %d, want %d", got, want)
+	}
+	if got, want := len(after.HostRules[0].Hosts), 1; got != want {
+		t.Fatalf("len(after.HostRules[0].Hosts) = %d, want %d", got, want)
+	}
+}
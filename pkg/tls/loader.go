@@ -0,0 +1,111 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tls loads the TLS materials an Ingress references out of
+// Kubernetes Secrets.
+package tls
+
+import (
+	"fmt"
+
+	api_v1 "k8s.io/api/core/v1"
+	listers_v1 "k8s.io/client-go/listers/core/v1"
+
+	"k8s.io/ingress-gce/pkg/loadbalancers"
+)
+
+// caCertSecretKey is the Secret data key client-cert-trust CA bundles are
+// expected under, mirroring how kubernetes.io/tls Secrets use tls.crt/tls.key.
+const caCertSecretKey = "ca.crt"
+
+// TLSSecretLoader loads the TLS materials referenced by an Ingress's TLS
+// spec and pass-tls-cert client-trust configuration out of Kubernetes
+// Secrets.
+type TLSSecretLoader interface {
+	// GetTLSCertFromSecret returns the serving certificate/key pair stored
+	// in the kubernetes.io/tls Secret named secretName in namespace.
+	GetTLSCertFromSecret(namespace, secretName string) (*loadbalancers.TLSCerts, error)
+	// GetCACertFromSecret returns the PEM-encoded CA bundle stored under
+	// the "ca.crt" key of the Secret named secretName in namespace. It is
+	// used to validate client certificates when an Ingress enables
+	// pass-tls-cert together with a client-cert-trust Secret.
+	GetCACertFromSecret(namespace, secretName string) (string, error)
+}
+
+// secretTLSLoader is the production TLSSecretLoader, backed by a Secret
+// lister.
+type secretTLSLoader struct {
+	lister listers_v1.SecretLister
+}
+
+// NewSecretTLSLoader returns a TLSSecretLoader backed by secretLister.
+func NewSecretTLSLoader(secretLister listers_v1.SecretLister) TLSSecretLoader {
+	return &secretTLSLoader{lister: secretLister}
+}
+
+// GetTLSCertFromSecret implements TLSSecretLoader.
+func (t *secretTLSLoader) GetTLSCertFromSecret(namespace, secretName string) (*loadbalancers.TLSCerts, error) {
+	secret, err := t.lister.Secrets(namespace).Get(secretName)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving secret %v/%v: %v", namespace, secretName, err)
+	}
+	cert, ok := secret.Data[api_v1.TLSCertKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %v/%v has no %v", namespace, secretName, api_v1.TLSCertKey)
+	}
+	key, ok := secret.Data[api_v1.TLSPrivateKeyKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %v/%v has no %v", namespace, secretName, api_v1.TLSPrivateKeyKey)
+	}
+	return &loadbalancers.TLSCerts{Key: string(key), Cert: string(cert)}, nil
+}
+
+// GetCACertFromSecret implements TLSSecretLoader.
+func (t *secretTLSLoader) GetCACertFromSecret(namespace, secretName string) (string, error) {
+	secret, err := t.lister.Secrets(namespace).Get(secretName)
+	if err != nil {
+		return "", fmt.Errorf("error retrieving secret %v/%v: %v", namespace, secretName, err)
+	}
+	ca, ok := secret.Data[caCertSecretKey]
+	if !ok {
+		return "", fmt.Errorf("secret %v/%v has no %v", namespace, secretName, caCertSecretKey)
+	}
+	return string(ca), nil
+}
+
+// FakeTLSSecretLoader is a TLSSecretLoader for tests; it serves certs and CA
+// bundles out of in-memory maps keyed by secret name instead of hitting the
+// API server.
+type FakeTLSSecretLoader struct {
+	FakeCerts   map[string]*loadbalancers.TLSCerts
+	FakeCACerts map[string]string
+}
+
+// GetTLSCertFromSecret implements TLSSecretLoader.
+func (f *FakeTLSSecretLoader) GetTLSCertFromSecret(namespace, secretName string) (*loadbalancers.TLSCerts, error) {
+	if cert, ok := f.FakeCerts[secretName]; ok {
+		return cert, nil
+	}
+	return nil, fmt.Errorf("no fake cert for secret %v", secretName)
+}
+
+// GetCACertFromSecret implements TLSSecretLoader.
+func (f *FakeTLSSecretLoader) GetCACertFromSecret(namespace, secretName string) (string, error) {
+	if ca, ok := f.FakeCACerts[secretName]; ok {
+		return ca, nil
+	}
+	return "", fmt.Errorf("no fake CA cert for secret %v", secretName)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// ServicePort models a GCE-addressable backend for a Kubernetes Service.
+type ServicePort struct {
+	NodePort int64
+}
+
+// PathRule encodes a single `path -> backend` mapping within a host's rule set.
+type PathRule struct {
+	Path    string
+	Backend ServicePort
+}
+
+// PrimitivePathMap is a simplified host->path->backend representation used
+// by tests to describe the Ingress rules a fixture should create.
+type PrimitivePathMap map[string]map[string]string
+
+// GCEURLMap mirrors the host/path-matcher/path-rule structure of a GCE
+// UrlMap, but is built incrementally as Ingress rules are translated. Hosts
+// whose PathRules are identical (the common case for an apex/www pair, or a
+// fleet of tenant hostnames fronting the same set of Services) share a
+// single PathMatcher instead of each minting their own, which keeps large,
+// homogenous Ingresses well under GCE's per-UrlMap PathMatcher quota.
+type GCEURLMap struct {
+	DefaultBackend int64
+
+	// matchers maps a PathMatcher name to the PathRules it serves.
+	matchers map[string][]PathRule
+	// hostToMatcher maps a host to the name of the PathMatcher it is
+	// attached to.
+	hostToMatcher map[string]string
+	// ruleSetToMatcher dedupes PathMatchers by the normalized rule set
+	// they serve, so that PutPathRulesForHost can detect a repeat.
+	ruleSetToMatcher map[string]string
+}
+
+// NewGCEURLMap returns an empty GCEURLMap.
+func NewGCEURLMap() *GCEURLMap {
+	return &GCEURLMap{
+		matchers:         map[string][]PathRule{},
+		hostToMatcher:    map[string]string{},
+		ruleSetToMatcher: map[string]string{},
+	}
+}
+
+// PutPathRulesForHost associates rules with hostname. If an existing
+// PathMatcher already serves an identical (normalized) rule set, hostname is
+// attached to that matcher instead of a new one being minted for it.
+func (g *GCEURLMap) PutPathRulesForHost(hostname string, rules []PathRule) {
+	key := hashPathRules(rules)
+	if name, ok := g.ruleSetToMatcher[key]; ok {
+		g.AttachHosts(name, []string{hostname})
+		return
+	}
+	name := fmt.Sprintf("pm-%s", key)
+	g.PutSharedPathRules(name, rules)
+	g.ruleSetToMatcher[key] = name
+	g.AttachHosts(name, []string{hostname})
+}
+
+// PutSharedPathRules registers (or overwrites) the PathMatcher called name
+// with rules. Callers that already know a batch of hosts share an identical
+// rule set should call this once, followed by AttachHosts, rather than
+// calling PutPathRulesForHost per host and re-hashing the same rules.
+func (g *GCEURLMap) PutSharedPathRules(name string, rules []PathRule) {
+	g.matchers[name] = rules
+}
+
+// AttachHosts points each of hosts at the PathMatcher named matcherName.
+func (g *GCEURLMap) AttachHosts(matcherName string, hosts []string) {
+	for _, h := range hosts {
+		g.hostToMatcher[h] = matcherName
+	}
+}
+
+// PathMatchers returns the distinct PathMatchers registered on this
+// GCEURLMap, keyed by name.
+func (g *GCEURLMap) PathMatchers() map[string][]PathRule {
+	return g.matchers
+}
+
+// HostRules returns the PathMatcher name each host is currently attached to.
+func (g *GCEURLMap) HostRules() map[string]string {
+	return g.hostToMatcher
+}
+
+// SamePathRuleSet reports whether a and b contain the same (path, backend)
+// pairs, ignoring order. loadbalancers.FakeLoadBalancers.CheckURLMap and
+// ControllerFixture.WaitForURLMap both compare a PathMatcher's rules this
+// way, since map iteration over GCEURLMap's internals never guarantees an
+// order callers can rely on.
+func SamePathRuleSet(a, b []PathRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	normalize := func(rules []PathRule) []string {
+		out := make([]string, len(rules))
+		for i, r := range rules {
+			out[i] = fmt.Sprintf("%s=%d", r.Path, r.Backend.NodePort)
+		}
+		sort.Strings(out)
+		return out
+	}
+	an, bn := normalize(a), normalize(b)
+	for i := range an {
+		if an[i] != bn[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hashPathRules produces a deterministic key for a set of PathRules so that
+// hosts with identical rule sets, regardless of insertion order, dedupe to
+// the same PathMatcher.
+func hashPathRules(rules []PathRule) string {
+	normalized := make([]string, 0, len(rules))
+	for _, r := range rules {
+		normalized = append(normalized, fmt.Sprintf("%s=%d", r.Path, r.Backend.NodePort))
+	}
+	sort.Strings(normalized)
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(normalized, ",")))
+	return fmt.Sprintf("%x", h.Sum64())
+}
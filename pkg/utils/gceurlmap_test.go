@@ -0,0 +1,75 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import "testing"
+
+func TestGCEURLMapSharesIdenticalPathMatchers(t *testing.T) {
+	urlMap := NewGCEURLMap()
+	rules := []PathRule{{Path: "/foo", Backend: ServicePort{NodePort: 30001}}}
+	hosts := []string{"foo.example.com", "bar.example.com", "baz.example.com"}
+	for _, h := range hosts {
+		urlMap.PutPathRulesForHost(h, rules)
+	}
+
+	if got, want := len(urlMap.HostRules()), len(hosts); got != want {
+		t.Fatalf("len(urlMap.HostRules()) = %d, want %d", got, want)
+	}
+	if got := len(urlMap.PathMatchers()); got != 1 {
+		t.Fatalf("len(urlMap.PathMatchers()) = %d, want 1", got)
+	}
+	if got, want := len(urlMap.PathMatchers()), len(hosts); got >= want {
+		t.Fatalf("len(urlMap.PathMatchers()) = %d, want < %d", got, want)
+	}
+}
+
+func TestGCEURLMapDoesNotShareDistinctPathMatchers(t *testing.T) {
+	urlMap := NewGCEURLMap()
+	urlMap.PutPathRulesForHost("foo.example.com", []PathRule{{Path: "/foo", Backend: ServicePort{NodePort: 30001}}})
+	urlMap.PutPathRulesForHost("bar.example.com", []PathRule{{Path: "/bar", Backend: ServicePort{NodePort: 30002}}})
+
+	if got, want := len(urlMap.PathMatchers()), 2; got != want {
+		t.Fatalf("len(urlMap.PathMatchers()) = %d, want %d", got, want)
+	}
+}
+
+func TestGCEURLMapRemovingOneHostKeepsSharedMatcher(t *testing.T) {
+	rules := []PathRule{{Path: "/foo", Backend: ServicePort{NodePort: 30001}}}
+
+	// LoadBalancerController never mutates a previous sync's GCEURLMap in
+	// place; every sync calls translateIngress to build a fresh one from
+	// the Ingress's current rules. So "removing a host" really means: the
+	// next sync's GCEURLMap simply never calls PutPathRulesForHost for it.
+	// The property worth testing is that bar.example.com's PathMatcher
+	// identity and rules don't depend on whether foo.example.com happened
+	// to share it in a previous (or sibling) GCEURLMap.
+	withBothHosts := NewGCEURLMap()
+	withBothHosts.PutPathRulesForHost("foo.example.com", rules)
+	withBothHosts.PutPathRulesForHost("bar.example.com", rules)
+
+	afterRemoval := NewGCEURLMap()
+	afterRemoval.PutPathRulesForHost("bar.example.com", rules)
+
+	matcherBefore := withBothHosts.HostRules()["bar.example.com"]
+	matcherAfter := afterRemoval.HostRules()["bar.example.com"]
+	if matcherBefore != matcherAfter {
+		t.Fatalf("bar.example.com's PathMatcher name changed from %q to %q once foo.example.com stopped sharing it", matcherBefore, matcherAfter)
+	}
+	if _, ok := afterRemoval.PathMatchers()[matcherAfter]; !ok {
+		t.Fatalf("PathMatcher %q missing once foo.example.com was removed from the Ingress", matcherAfter)
+	}
+}
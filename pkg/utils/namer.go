@@ -0,0 +1,82 @@
+/*
+Copyright 2015 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Namer names every GCE resource a cluster's Ingress controller creates,
+// deriving them from a cluster UID so that multiple clusters (or multiple
+// runs against the same project) never collide.
+type Namer struct {
+	mu sync.Mutex
+
+	clusterName  string
+	firewallName string
+}
+
+// NewNamer returns a Namer for clusterName, whose firewall rule is initially
+// named firewallName.
+func NewNamer(clusterName, firewallName string) *Namer {
+	return &Namer{clusterName: clusterName, firewallName: firewallName}
+}
+
+// lbName turns an Ingress store key (namespace/name) into a GCE-safe name
+// root, stable across calls, that every other Namer method builds on.
+func (n *Namer) lbName(lbName string) string {
+	sanitized := strings.Replace(lbName, "/", "--", -1)
+	return fmt.Sprintf("%s--%s", sanitized, n.clusterName)
+}
+
+// UrlMap returns the UrlMap name for the load balancer named lbName.
+func (n *Namer) UrlMap(lbName string) string {
+	return fmt.Sprintf("k8s-um-%s", n.lbName(lbName))
+}
+
+// TargetProxy returns the target proxy name for the load balancer named
+// lbName, for the given protocol ("http" or "https").
+func (n *Namer) TargetProxy(lbName, protocol string) string {
+	return fmt.Sprintf("k8s-tp-%s-%s", protocol, n.lbName(lbName))
+}
+
+// ForwardingRule returns the forwarding rule name for the load balancer
+// named lbName, for the given protocol ("http" or "https").
+func (n *Namer) ForwardingRule(lbName, protocol string) string {
+	return fmt.Sprintf("k8s-fw-%s-%s", protocol, n.lbName(lbName))
+}
+
+// IGBackend returns the BackendService name fronting nodePort.
+func (n *Namer) IGBackend(nodePort int64) string {
+	return fmt.Sprintf("k8s-be-%d--%s", nodePort, n.clusterName)
+}
+
+// FirewallRule returns the name of the cluster's single firewall rule.
+func (n *Namer) FirewallRule() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.firewallName
+}
+
+// SetFirewall overrides the name of the cluster's firewall rule.
+func (n *Namer) SetFirewall(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.firewallName = name
+}